@@ -1,6 +1,13 @@
 package db
 
 import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
 	"gorm.io/gorm"
 )
 
@@ -8,18 +15,54 @@ import (
 type BlockType string
 
 const (
-	BlockTypeArtist BlockType = "artist"
-	BlockTypeTrack  BlockType = "track"
+	BlockTypeArtist  BlockType = "artist"
+	BlockTypeTrack   BlockType = "track"
+	BlockTypeKeyword BlockType = "keyword" // Case-insensitive text in track, artist or album names
+	BlockTypeRegex   BlockType = "regex"   // Case-insensitive regular expression on track, artist or album names
+	BlockTypeAlbum   BlockType = "album"
+	BlockTypeLabel   BlockType = "label" // Whole words of the album's label and copyright lines, case-insensitive
+	BlockTypeGenre   BlockType = "genre" // Whole genre name of one of the track's artists, case-insensitive
 )
 
-// AddBlock adds a new block for a streamer using Spotify ID
-func AddBlock(db *gorm.DB, streamerID uint, blockType BlockType, spotifyID, name string) error {
+// IsNameBlockType checks if a block type is keyed by a lowercased name instead of a Spotify ID
+func IsNameBlockType(blockType BlockType) bool {
+	return blockType == BlockTypeLabel || blockType == BlockTypeGenre
+}
+
+// IsPatternBlockType checks if a block type matches names instead of Spotify IDs
+func IsPatternBlockType(blockType BlockType) bool {
+	return blockType == BlockTypeKeyword || blockType == BlockTypeRegex
+}
+
+// ValidateBlockPattern checks that a pattern can be used for a pattern block
+func ValidateBlockPattern(blockType BlockType, pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("pattern is required")
+	}
+	if blockType == BlockTypeRegex {
+		if _, err := regexp.Compile("(?i)" + pattern); err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+	}
+	return nil
+}
+
+// BlockOptions carries the optional context of a block
+type BlockOptions struct {
+	ExpiresAt  *time.Time // Nil for a permanent block
+	Reason     string
+	AddedBy    string
+	StreamOnly bool // Remove the block once the stream goes offline
+}
+
+// AddBlock adds a new block for a streamer using Spotify ID.
+// Adding an existing block again replaces its expiry, reason and author.
+func AddBlock(db *gorm.DB, streamerID uint, blockType BlockType, spotifyID, name string, opts BlockOptions) error {
 	// Check if block already exists
 	var existingBlock Block
-	err := db.Where("block_streamer_id = ? AND block_spotify_id = ?", streamerID, spotifyID).First(&existingBlock).Error
+	err := db.Where("block_streamer_id = ? AND block_type = ? AND block_spotify_id = ?", streamerID, string(blockType), spotifyID).First(&existingBlock).Error
 	if err == nil {
-		// Block already exists
-		return nil
+		return updateBlockOptions(db, &existingBlock, opts)
 	}
 
 	if err != gorm.ErrRecordNotFound {
@@ -32,11 +75,40 @@ func AddBlock(db *gorm.DB, streamerID uint, blockType BlockType, spotifyID, name
 		SpotifyID:  spotifyID,
 		Type:       string(blockType),
 		Name:       name,
+		ExpiresAt:  opts.ExpiresAt,
+		Reason:     opts.Reason,
+		AddedBy:    opts.AddedBy,
+		StreamOnly: opts.StreamOnly,
 	}
 
 	return db.Create(&block).Error
 }
 
+// updateBlockOptions applies the options of a repeated block to an existing block. A block is only ever
+// extended, never weakened, so a viewer's stream ban can't shorten a permanent block of the streamer.
+// The reason and author are replaced unless the new options are weaker.
+func updateBlockOptions(db *gorm.DB, block *Block, opts BlockOptions) error {
+	expiresAt := opts.ExpiresAt
+	if block.ExpiresAt == nil || (expiresAt != nil && block.ExpiresAt.After(*expiresAt)) {
+		expiresAt = block.ExpiresAt
+	}
+	streamOnly := block.StreamOnly && opts.StreamOnly
+
+	updates := map[string]interface{}{
+		"block_expires_at":  expiresAt,
+		"block_stream_only": streamOnly,
+	}
+
+	weaker := (opts.ExpiresAt != nil && (block.ExpiresAt == nil || opts.ExpiresAt.Before(*block.ExpiresAt))) ||
+		(opts.StreamOnly && !block.StreamOnly)
+	if !weaker {
+		updates["block_reason"] = opts.Reason
+		updates["block_added_by"] = opts.AddedBy
+	}
+
+	return db.Model(block).Updates(updates).Error
+}
+
 // RemoveBlock removes a block for a streamer by block ID
 func RemoveBlockByID(db *gorm.DB, streamerID uint, blockID uint) error {
 	return db.Where("block_id = ? AND block_streamer_id = ?", blockID, streamerID).Delete(&Block{}).Error
@@ -47,44 +119,205 @@ func RemoveBlock(db *gorm.DB, streamerID uint, spotifyID string) error {
 	return db.Where("block_streamer_id = ? AND block_spotify_id = ?", streamerID, spotifyID).Delete(&Block{}).Error
 }
 
-// IsBlocked checks if an artist or track is blocked for a streamer using Spotify IDs
-func IsBlocked(db *gorm.DB, streamerID uint, artistIDs []string, trackID string) bool {
-	var count int64
+// AddPatternBlock adds a keyword or regex block for a streamer
+func AddPatternBlock(db *gorm.DB, streamerID uint, blockType BlockType, pattern string, opts BlockOptions) error {
+	if err := ValidateBlockPattern(blockType, pattern); err != nil {
+		return err
+	}
+
+	// Check if block already exists
+	var existingBlock Block
+	err := db.Where("block_streamer_id = ? AND block_type = ? AND block_pattern = ?", streamerID, string(blockType), pattern).First(&existingBlock).Error
+	if err == nil {
+		return updateBlockOptions(db, &existingBlock, opts)
+	}
+
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	block := Block{
+		StreamerID: streamerID,
+		Type:       string(blockType),
+		Name:       pattern,
+		Pattern:    pattern,
+		ExpiresAt:  opts.ExpiresAt,
+		Reason:     opts.Reason,
+		AddedBy:    opts.AddedBy,
+		StreamOnly: opts.StreamOnly,
+	}
+
+	return db.Create(&block).Error
+}
+
+// BlockTarget describes a track checked against the blocklist.
+// Labels and genres need extra Spotify lookups, so they are only loaded when a label or genre block exists.
+type BlockTarget struct {
+	TrackID     string
+	TrackName   string
+	ArtistIDs   []string
+	ArtistNames []string
+	AlbumID     string
+	AlbumName   string
+	LoadLabels  func() []string // Label and copyright lines of the album
+	LoadGenres  func() []string // Genres of the track's artists
+}
+
+// BlockMatch describes the block that matched a track
+type BlockMatch struct {
+	Block Block
+	Field string // "track", "artist", "album", "label" or "genre"
+	Value string // Name that matched a pattern, label or genre block; empty for ID blocks
+}
+
+// CheckBlocked returns the first unexpired block matching the track, or nil if it isn't blocked
+func CheckBlocked(db *gorm.DB, streamerID uint, target BlockTarget) (*BlockMatch, error) {
+	blocks, err := GetBlocks(db, streamerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocks for streamer %d: %w", streamerID, err)
+	}
 
-	// Check if track is directly blocked
-	if trackID != "" {
-		db.Model(&Block{}).Where("block_streamer_id = ? AND block_spotify_id = ? AND block_type = ?",
-			streamerID, trackID, "track").Count(&count)
-		if count > 0 {
-			return true
+	var labels, genres []string
+	labelsLoaded, genresLoaded := false, false
+
+	for _, block := range blocks {
+		switch BlockType(block.Type) {
+		case BlockTypeTrack:
+			if target.TrackID != "" && block.SpotifyID == target.TrackID {
+				return &BlockMatch{Block: block, Field: "track"}, nil
+			}
+		case BlockTypeArtist:
+			for _, artistID := range target.ArtistIDs {
+				if block.SpotifyID == artistID {
+					return &BlockMatch{Block: block, Field: "artist"}, nil
+				}
+			}
+		case BlockTypeAlbum:
+			if target.AlbumID != "" && block.SpotifyID == target.AlbumID {
+				return &BlockMatch{Block: block, Field: "album"}, nil
+			}
+		case BlockTypeLabel:
+			if !labelsLoaded && target.LoadLabels != nil {
+				labels, labelsLoaded = target.LoadLabels(), true
+			}
+			if label := MatchLabel(block.SpotifyID, labels); label != "" {
+				return &BlockMatch{Block: block, Field: "label", Value: label}, nil
+			}
+		case BlockTypeGenre:
+			if !genresLoaded && target.LoadGenres != nil {
+				genres, genresLoaded = target.LoadGenres(), true
+			}
+			if genre := MatchGenre(block.SpotifyID, genres); genre != "" {
+				return &BlockMatch{Block: block, Field: "genre", Value: genre}, nil
+			}
+		case BlockTypeKeyword, BlockTypeRegex:
+			if match := matchPatternBlock(block, target); match != nil {
+				return match, nil
+			}
 		}
 	}
 
-	// Check if any of the artists are blocked
-	for _, artistID := range artistIDs {
-		db.Model(&Block{}).Where("block_streamer_id = ? AND block_spotify_id = ? AND block_type = ?",
-			streamerID, artistID, "artist").Count(&count)
-		if count > 0 {
-			return true
+	return nil, nil
+}
+
+// MatchLabel returns the first copyright line naming the blocked label, or an empty string.
+// The label has to appear as whole words, so blocking "emi" doesn't block a line mentioning "remix".
+func MatchLabel(blocked string, lines []string) string {
+	wanted := labelWords(blocked)
+	if wanted == "" {
+		return ""
+	}
+	for _, line := range lines {
+		if strings.Contains(" "+labelWords(line)+" ", " "+wanted+" ") {
+			return line
 		}
 	}
+	return ""
+}
 
-	return false
+// labelWords lowercases a label or copyright line and reduces everything but letters and digits to single spaces
+func labelWords(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
 }
 
-// GetBlocks returns all blocks for a streamer
+// MatchGenre returns the first genre equal to the lowercased blocked genre, or an empty string.
+// Genres are compared whole, so blocking "rap" doesn't block "trap" and "pop" doesn't block "k-pop".
+func MatchGenre(blocked string, genres []string) string {
+	if blocked == "" {
+		return ""
+	}
+	for _, genre := range genres {
+		if strings.ToLower(strings.TrimSpace(genre)) == blocked {
+			return genre
+		}
+	}
+	return ""
+}
+
+// matchPatternBlock applies a keyword or regex block to the track, artist and album names
+func matchPatternBlock(block Block, target BlockTarget) *BlockMatch {
+	var matches func(string) bool
+	if BlockType(block.Type) == BlockTypeRegex {
+		pattern, err := regexp.Compile("(?i)" + block.Pattern)
+		if err != nil {
+			log.Printf("Skipping block %d with invalid pattern %q: %v", block.ID, block.Pattern, err)
+			return nil
+		}
+		matches = pattern.MatchString
+	} else {
+		keyword := strings.ToLower(block.Pattern)
+		matches = func(name string) bool {
+			return strings.Contains(strings.ToLower(name), keyword)
+		}
+	}
+
+	if target.TrackName != "" && matches(target.TrackName) {
+		return &BlockMatch{Block: block, Field: "track", Value: target.TrackName}
+	}
+	for _, artistName := range target.ArtistNames {
+		if matches(artistName) {
+			return &BlockMatch{Block: block, Field: "artist", Value: artistName}
+		}
+	}
+	if target.AlbumName != "" && matches(target.AlbumName) {
+		return &BlockMatch{Block: block, Field: "album", Value: target.AlbumName}
+	}
+	return nil
+}
+
+// GetBlocks returns all unexpired blocks for a streamer
 func GetBlocks(db *gorm.DB, streamerID uint) ([]Block, error) {
 	var blocks []Block
-	err := db.Where("block_streamer_id = ?", streamerID).Find(&blocks).Error
+	err := db.Where("block_streamer_id = ? AND (block_expires_at IS NULL OR block_expires_at > ?)", streamerID, time.Now()).Find(&blocks).Error
 	return blocks, err
 }
 
+// DeleteExpiredBlocks removes the expired blocks of a streamer and returns how many were removed
+func DeleteExpiredBlocks(db *gorm.DB, streamerID uint) (int64, error) {
+	result := db.Where("block_streamer_id = ? AND block_expires_at IS NOT NULL AND block_expires_at <= ?", streamerID, time.Now()).Delete(&Block{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteStreamOnlyBlocks removes the blocks of a streamer that only last for the current stream
+func DeleteStreamOnlyBlocks(db *gorm.DB, streamerID uint) (int64, error) {
+	result := db.Where("block_streamer_id = ? AND block_stream_only = ?", streamerID, true).Delete(&Block{})
+	return result.RowsAffected, result.Error
+}
+
 // BlockInfo represents block information for API responses
 type BlockInfo struct {
-	ID        uint   `json:"id"`
-	SpotifyID string `json:"spotify_id"`
-	Name      string `json:"name"`
-	Type      string `json:"type"` // "artist" or "track"
+	ID         uint   `json:"id"`
+	SpotifyID  string `json:"spotify_id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"` // "artist", "track", "album", "label", "genre", "keyword" or "regex"
+	Pattern    string `json:"pattern,omitempty"`
+	ExpiresAt  string `json:"expires_at,omitempty"` // RFC 3339; empty for permanent blocks
+	Reason     string `json:"reason,omitempty"`
+	AddedBy    string `json:"added_by,omitempty"`
+	StreamOnly bool   `json:"stream_only,omitempty"`
+	AddedAt    string `json:"added_at"`
 }
 
 // GetBlocksInfo returns formatted block information for API responses
@@ -96,12 +329,21 @@ func GetBlocksInfo(db *gorm.DB, streamerID uint) ([]BlockInfo, error) {
 
 	var blocksInfo []BlockInfo
 	for _, block := range blocks {
-		blocksInfo = append(blocksInfo, BlockInfo{
-			ID:        block.ID,
-			SpotifyID: block.SpotifyID,
-			Name:      block.Name,
-			Type:      block.Type,
-		})
+		info := BlockInfo{
+			ID:         block.ID,
+			SpotifyID:  block.SpotifyID,
+			Name:       block.Name,
+			Type:       block.Type,
+			Pattern:    block.Pattern,
+			Reason:     block.Reason,
+			AddedBy:    block.AddedBy,
+			StreamOnly: block.StreamOnly,
+			AddedAt:    block.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		if block.ExpiresAt != nil {
+			info.ExpiresAt = block.ExpiresAt.Format(time.RFC3339)
+		}
+		blocksInfo = append(blocksInfo, info)
 	}
 
 	return blocksInfo, nil
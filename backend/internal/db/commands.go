@@ -7,6 +7,55 @@ import (
 	"gorm.io/gorm"
 )
 
+// Command types that can be configured by a streamer
+const (
+	CommandTypeRequest = "request"
+	CommandTypeBlock   = "block"
+	CommandTypeVolume  = "volume"
+	CommandTypeSkip    = "skip"
+	CommandTypeQueue   = "queue"
+)
+
+// Command permission levels, from least to most privileged
+const (
+	PermissionEveryone     = "everyone"
+	PermissionFollower     = "follower"
+	PermissionSubscriber   = "subscriber"
+	PermissionVIP          = "vip"
+	PermissionModerator    = "moderator"
+	PermissionBotModerator = "bot_moderator"
+	PermissionBroadcaster  = "broadcaster"
+)
+
+// IsValidPermission checks if a permission level is known
+func IsValidPermission(permission string) bool {
+	switch permission {
+	case PermissionEveryone, PermissionFollower, PermissionSubscriber, PermissionVIP,
+		PermissionModerator, PermissionBotModerator, PermissionBroadcaster:
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultCommandPermission returns the permission a command type requires when none is configured
+func DefaultCommandPermission(commandType string) string {
+	switch commandType {
+	case CommandTypeBlock, CommandTypeVolume, CommandTypeSkip:
+		return PermissionModerator
+	default:
+		return PermissionEveryone
+	}
+}
+
+// EffectivePermission returns the permission required to use the command
+func (c *Command) EffectivePermission() string {
+	if c.Permission == "" {
+		return DefaultCommandPermission(c.Type)
+	}
+	return c.Permission
+}
+
 // GetStreamerCommands retrieves all commands for a streamer
 func GetStreamerCommands(db *gorm.DB, streamerID uint) ([]Command, error) {
 	var commands []Command
@@ -27,8 +76,19 @@ func GetCommandByType(db *gorm.DB, streamerID uint, commandType string) (*Comman
 	return &command, nil
 }
 
-// CreateOrUpdateCommand creates or updates a command for a streamer
-func CreateOrUpdateCommand(db *gorm.DB, streamerID uint, commandType, name string, enabled bool) error {
+// GetEnabledCommandByName retrieves an enabled command by its chat name (without the "!" prefix)
+func GetEnabledCommandByName(db *gorm.DB, streamerID uint, name string) (*Command, error) {
+	var command Command
+	err := db.Where("command_streamer_id = ? AND LOWER(command_name) = LOWER(?) AND command_enabled = ?", streamerID, name, true).First(&command).Error
+	if err != nil {
+		return nil, err
+	}
+	return &command, nil
+}
+
+// CreateOrUpdateCommand creates or updates a command for a streamer.
+// An empty permission keeps the current one (or the type's default for new commands).
+func CreateOrUpdateCommand(db *gorm.DB, streamerID uint, commandType, name string, enabled bool, permission string) error {
 	var command Command
 	err := db.Where("command_streamer_id = ? AND command_type = ?", streamerID, commandType).First(&command).Error
 
@@ -39,6 +99,7 @@ func CreateOrUpdateCommand(db *gorm.DB, streamerID uint, commandType, name strin
 			Type:       commandType,
 			Name:       name,
 			IsEnabled:  enabled,
+			Permission: permission,
 		}
 		err = db.Create(&command).Error
 		if err != nil {
@@ -49,6 +110,9 @@ func CreateOrUpdateCommand(db *gorm.DB, streamerID uint, commandType, name strin
 		// Command exists, update it
 		command.Name = name
 		command.IsEnabled = enabled
+		if permission != "" {
+			command.Permission = permission
+		}
 		err = db.Save(&command).Error
 		if err != nil {
 			return fmt.Errorf("failed to update command %s for streamer %d: %w", commandType, streamerID, err)
@@ -59,6 +123,22 @@ func CreateOrUpdateCommand(db *gorm.DB, streamerID uint, commandType, name strin
 	return nil
 }
 
+// MigrateLegacyRequestCommands renames request commands still using the old "sr" default,
+// which hid the built-in !sr recent songs command. It runs once, so the name can't be picked again
+// by accident; commands now reject it anyway.
+func MigrateLegacyRequestCommands(db *gorm.DB) error {
+	result := db.Model(&Command{}).
+		Where("command_type = ? AND LOWER(command_name) = ?", CommandTypeRequest, "sr").
+		Update("command_name", "songrequest")
+	if result.Error != nil {
+		return fmt.Errorf("failed to rename legacy request commands: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Renamed %d request commands from !sr to !songrequest", result.RowsAffected)
+	}
+	return nil
+}
+
 // DeleteCommand deletes a command for a streamer
 func DeleteCommand(db *gorm.DB, streamerID uint, commandType string) error {
 	err := db.Where("command_streamer_id = ? AND command_type = ?", streamerID, commandType).Delete(&Command{}).Error
@@ -76,15 +156,16 @@ func InitializeDefaultCommands(db *gorm.DB, streamerID uint) error {
 		Name    string
 		Enabled bool
 	}{
-		{"request", "sr", true},
-		{"block", "block", true},
-		{"volume", "volume", true},
-		{"skip", "skip", true},
-		{"queue", "queue", true},
+		{CommandTypeRequest, "songrequest", true},
+		{CommandTypeBlock, "block", true},
+		{CommandTypeVolume, "volume", true},
+		{CommandTypeSkip, "skip", true},
+		{CommandTypeQueue, "queue", true},
 	}
 
 	for _, cmd := range defaultCommands {
-		err := CreateOrUpdateCommand(db, streamerID, cmd.Type, cmd.Name, cmd.Enabled)
+		// An empty permission keeps the one the streamer picked; new commands use the type's default
+		err := CreateOrUpdateCommand(db, streamerID, cmd.Type, cmd.Name, cmd.Enabled, "")
 		if err != nil {
 			return fmt.Errorf("failed to initialize default command %s: %w", cmd.Type, err)
 		}
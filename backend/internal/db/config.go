@@ -2,15 +2,50 @@ package db
 
 import (
 	"strconv"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
 // ConfigKeys for various settings
 const (
-	ConfigKeyMaxSongLength    = "max_song_length"
-	ConfigKeyCooldownSameSong = "cooldown_same_song"
-	ConfigKeyWebUIEnabled     = "web_ui_enabled"
+	ConfigKeyMaxSongLength      = "max_song_length"
+	ConfigKeyCooldownSameSong   = "cooldown_same_song"
+	ConfigKeyCooldownSameArtist = "cooldown_same_artist"
+	ConfigKeyCooldownSameAlbum  = "cooldown_same_album"
+	ConfigKeyDuplicateHold      = "duplicate_hold"
+	ConfigKeyWebUIEnabled       = "web_ui_enabled"
+	ConfigKeyPriorityTiers      = "priority_tiers"
+	ConfigKeyPriorityBitsMin    = "priority_bits_min"
+	ConfigKeyMaxPendingPerUser  = "max_pending_per_user"
+	ConfigKeyMaxUserRequests    = "max_user_requests"
+	ConfigKeyUserRequestWindow  = "user_request_window"
+	ConfigKeyUserRequestGap     = "user_request_gap"
+	ConfigKeyExplicitFilter     = "explicit_filter"
+	ConfigKeyExplicitFallback   = "explicit_clean_fallback"
+	ConfigKeyMinSongLength      = "min_song_length"
+	ConfigKeyMinPopularity      = "min_popularity"
+	ConfigKeyMinReleaseYear     = "min_release_year"
+	ConfigKeyMaxReleaseYear     = "max_release_year"
+	ConfigKeyBlockedGenres      = "blocked_genres"
+	ConfigKeyPlaylistAllowlist  = "playlist_allowlist"
+	ConfigKeyBlockCommandSkip   = "block_command_skip"
+	ConfigKeyVolumeRewardStep   = "volume_reward_step"
+	ConfigKeyVolumeRewardMin    = "volume_reward_min"
+	ConfigKeyVolumeRewardMax    = "volume_reward_max"
+	ConfigKeyRequestsClosed     = "requests_closed"
+	ConfigKeyMaxQueueLength     = "max_queue_length"
+	ConfigKeyMaxQueueDuration   = "max_queue_duration"
+	ConfigKeyRedemptionMaxAge   = "redemption_max_age"
+	ConfigKeyHeldRequestTimeout = "held_request_timeout"
+	ConfigKeyPreferredDevice    = "preferred_device_id"
+)
+
+// Explicit content filter modes
+const (
+	ExplicitFilterAllow       = "allow"
+	ExplicitFilterBlock       = "block"
+	ExplicitFilterSubscribers = "subscribers" // Only subscribers may request explicit tracks
 )
 
 // GetConfig retrieves a configuration value for a streamer
@@ -94,7 +129,154 @@ func GetCooldownSameSong(db *gorm.DB, streamerID uint) int {
 	return GetConfigInt(db, streamerID, ConfigKeyCooldownSameSong, 3600) // 1 hour default
 }
 
+// GetDuplicateHold returns how long in seconds after it played a track is rejected as a duplicate (default: 1 hour, 0 = disabled)
+func GetDuplicateHold(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyDuplicateHold, 3600)
+}
+
+// GetCooldownSameArtist returns the cooldown for requests of the same artist in seconds (default: 0, no cooldown)
+func GetCooldownSameArtist(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyCooldownSameArtist, 0)
+}
+
+// GetCooldownSameAlbum returns the cooldown for requests from the same album in seconds (default: 0, no cooldown)
+func GetCooldownSameAlbum(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyCooldownSameAlbum, 0)
+}
+
 // IsWebUIEnabled returns whether the web UI is enabled for a streamer
 func IsWebUIEnabled(db *gorm.DB, streamerID uint) bool {
 	return GetConfigBool(db, streamerID, ConfigKeyWebUIEnabled, true) // enabled by default
 }
+
+// GetPriorityTiers returns the enabled priority tiers, highest priority first.
+// Tiers left out of the list are treated as regular requests.
+func GetPriorityTiers(db *gorm.DB, streamerID uint) []string {
+	value, err := GetConfig(db, streamerID, ConfigKeyPriorityTiers)
+	if err != nil || value == "" {
+		return DefaultPriorityTiers
+	}
+
+	var tiers []string
+	seen := make(map[string]bool)
+	for _, tier := range strings.Split(value, ",") {
+		tier = strings.ToLower(strings.TrimSpace(tier))
+		if IsValidPriorityTier(tier) && !seen[tier] {
+			seen[tier] = true
+			tiers = append(tiers, tier)
+		}
+	}
+
+	if len(tiers) == 0 {
+		return DefaultPriorityTiers
+	}
+	return tiers
+}
+
+// SetPriorityTiers sets the priority tier order, highest priority first
+func SetPriorityTiers(db *gorm.DB, streamerID uint, tiers []string) error {
+	return SetConfig(db, streamerID, ConfigKeyPriorityTiers, strings.Join(tiers, ","))
+}
+
+// GetPriorityBitsMin returns the minimum bits a chat request must carry to use the bits tier (default: 100)
+func GetPriorityBitsMin(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyPriorityBitsMin, 100)
+}
+
+// GetMaxPendingPerUser returns how many requests one viewer may have waiting in the queue (default: 0 = unlimited)
+func GetMaxPendingPerUser(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyMaxPendingPerUser, 0)
+}
+
+// GetMaxUserRequests returns how many requests one viewer may make per request window (default: 0 = unlimited)
+func GetMaxUserRequests(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyMaxUserRequests, 0)
+}
+
+// GetUserRequestWindow returns the rolling window for the per-viewer request limit in seconds (default: 1 hour)
+func GetUserRequestWindow(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyUserRequestWindow, 3600)
+}
+
+// GetUserRequestGap returns the minimum time between two requests of one viewer in seconds (default: 0 = none)
+func GetUserRequestGap(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyUserRequestGap, 0)
+}
+
+// IsValidExplicitFilter checks if an explicit content filter mode is known
+func IsValidExplicitFilter(mode string) bool {
+	switch mode {
+	case ExplicitFilterAllow, ExplicitFilterBlock, ExplicitFilterSubscribers:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetExplicitFilter returns the explicit content filter mode (default: allow)
+func GetExplicitFilter(db *gorm.DB, streamerID uint) string {
+	mode, err := GetConfig(db, streamerID, ConfigKeyExplicitFilter)
+	if err != nil || !IsValidExplicitFilter(mode) {
+		return ExplicitFilterAllow
+	}
+	return mode
+}
+
+// IsExplicitFallbackEnabled returns whether filtered explicit tracks are swapped for a clean version (default: enabled)
+func IsExplicitFallbackEnabled(db *gorm.DB, streamerID uint) bool {
+	return GetConfigBool(db, streamerID, ConfigKeyExplicitFallback, true)
+}
+
+// IsBlockCommandSkipEnabled returns whether the block chat command also skips the blocked track (default: enabled)
+func IsBlockCommandSkipEnabled(db *gorm.DB, streamerID uint) bool {
+	return GetConfigBool(db, streamerID, ConfigKeyBlockCommandSkip, true)
+}
+
+// GetVolumeRewardStep returns how many percent the volume rewards change the volume by (default: 10)
+func GetVolumeRewardStep(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyVolumeRewardStep, 10)
+}
+
+// GetVolumeRewardBounds returns the lowest and highest volume the volume rewards may set (default: 0-100)
+func GetVolumeRewardBounds(db *gorm.DB, streamerID uint) (int, int) {
+	return GetConfigInt(db, streamerID, ConfigKeyVolumeRewardMin, 0), GetConfigInt(db, streamerID, ConfigKeyVolumeRewardMax, 100)
+}
+
+// AreRequestsClosed returns whether the streamer has closed song requests (default: open)
+func AreRequestsClosed(db *gorm.DB, streamerID uint) bool {
+	return GetConfigBool(db, streamerID, ConfigKeyRequestsClosed, false)
+}
+
+// GetMaxQueueLength returns how many requests may wait in the queue (default: 0 = unlimited)
+func GetMaxQueueLength(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyMaxQueueLength, 0)
+}
+
+// GetMaxQueueDuration returns the total length in seconds the queued tracks may reach (default: 0 = unlimited)
+func GetMaxQueueDuration(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyMaxQueueDuration, 0)
+}
+
+// GetRedemptionMaxAge returns how old in seconds a missed redemption may be to still be processed on recovery (default: 900)
+func GetRedemptionMaxAge(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyRedemptionMaxAge, 900)
+}
+
+// GetHeldRequestTimeout returns how long in seconds requests wait for an active Spotify device before they are refunded (default: 600, 0 = wait indefinitely)
+func GetHeldRequestTimeout(db *gorm.DB, streamerID uint) int {
+	return GetConfigInt(db, streamerID, ConfigKeyHeldRequestTimeout, 600)
+}
+
+// GetPreferredDevice returns the Spotify device the bot targets (default: none, the active device is used)
+func GetPreferredDevice(db *gorm.DB, streamerID uint) string {
+	deviceID, err := GetConfig(db, streamerID, ConfigKeyPreferredDevice)
+	if err != nil {
+		return ""
+	}
+	return deviceID
+}
+
+// IsPlaylistAllowlistEnabled returns whether only tracks from allowed playlists may be requested (default: disabled)
+func IsPlaylistAllowlistEnabled(db *gorm.DB, streamerID uint) bool {
+	return GetConfigBool(db, streamerID, ConfigKeyPlaylistAllowlist, false)
+}
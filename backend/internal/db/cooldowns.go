@@ -0,0 +1,101 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Cooldown kinds
+const (
+	CooldownKindTrack  = "track"
+	CooldownKindArtist = "artist"
+	CooldownKindAlbum  = "album"
+	CooldownKindUser   = "user"
+)
+
+// CooldownKinds lists the cooldown kinds in the order they are checked
+var CooldownKinds = []string{CooldownKindTrack, CooldownKindArtist, CooldownKindAlbum, CooldownKindUser}
+
+// CooldownSubject is something a cooldown applies to
+type CooldownSubject struct {
+	Kind string
+	Key  string
+	Name string // Readable name for chat replies
+}
+
+// GetCooldownLengths returns the cooldown length in seconds of every kind for a streamer; 0 = no cooldown
+func GetCooldownLengths(db *gorm.DB, streamerID uint) map[string]int {
+	return map[string]int{
+		CooldownKindTrack:  GetCooldownSameSong(db, streamerID),
+		CooldownKindArtist: GetCooldownSameArtist(db, streamerID),
+		CooldownKindAlbum:  GetCooldownSameAlbum(db, streamerID),
+		CooldownKindUser:   GetUserRequestGap(db, streamerID),
+	}
+}
+
+// StartCooldowns (re)starts the cooldowns of the given subjects
+func StartCooldowns(db *gorm.DB, streamerID uint, subjects []CooldownSubject) error {
+	if len(subjects) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	cooldowns := make([]Cooldown, 0, len(subjects))
+	for _, subject := range subjects {
+		cooldowns = append(cooldowns, Cooldown{
+			StreamerID: streamerID,
+			Kind:       subject.Kind,
+			Key:        subject.Key,
+			Name:       subject.Name,
+			StartedAt:  now,
+		})
+	}
+
+	err := db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"cd_name", "cd_started_at"}),
+	}).Create(&cooldowns).Error
+	if err != nil {
+		return fmt.Errorf("failed to start cooldowns for streamer %d: %w", streamerID, err)
+	}
+	return nil
+}
+
+// GetCooldowns returns the stored cooldowns of the given subjects
+func GetCooldowns(db *gorm.DB, streamerID uint, subjects []CooldownSubject) ([]Cooldown, error) {
+	if len(subjects) == 0 {
+		return nil, nil
+	}
+
+	wanted := make(map[string]bool)
+	var keys []string
+	for _, subject := range subjects {
+		wanted[subject.Kind+":"+subject.Key] = true
+		keys = append(keys, subject.Key)
+	}
+
+	var found []Cooldown
+	if err := db.Where("cd_streamer_id = ? AND cd_key IN ?", streamerID, keys).Find(&found).Error; err != nil {
+		return nil, fmt.Errorf("failed to get cooldowns for streamer %d: %w", streamerID, err)
+	}
+
+	// Keys are only unique per kind
+	var cooldowns []Cooldown
+	for _, cooldown := range found {
+		if wanted[cooldown.Kind+":"+cooldown.Key] {
+			cooldowns = append(cooldowns, cooldown)
+		}
+	}
+	return cooldowns, nil
+}
+
+// DeleteCooldownsBefore removes cooldowns started before the given time and returns how many were removed
+func DeleteCooldownsBefore(db *gorm.DB, before time.Time) (int64, error) {
+	result := db.Where("cd_started_at < ?", before).Delete(&Cooldown{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete old cooldowns: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
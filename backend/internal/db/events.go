@@ -0,0 +1,47 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClaimEvent records an event key for the given time to live.
+// Returns false if the key was already claimed and hasn't expired yet.
+func ClaimEvent(db *gorm.DB, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	// An expired claim may still be waiting for cleanup
+	if err := db.Where("event_key = ? AND event_expires_at <= ?", key, now).Delete(&ProcessedEvent{}).Error; err != nil {
+		return false, fmt.Errorf("failed to clear expired event %s: %w", key, err)
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&ProcessedEvent{
+		Key:       key,
+		ExpiresAt: now.Add(ttl),
+	})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim event %s: %w", key, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteExpiredEvents removes expired event claims and returns how many were removed
+func DeleteExpiredEvents(db *gorm.DB) (int64, error) {
+	result := db.Where("event_expires_at <= ?", time.Now()).Delete(&ProcessedEvent{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired events: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// ReleaseEvent forgets an event claim, so the event can be handled again
+func ReleaseEvent(db *gorm.DB, key string) error {
+	if err := db.Where("event_key = ?", key).Delete(&ProcessedEvent{}).Error; err != nil {
+		return fmt.Errorf("failed to release event %s: %w", key, err)
+	}
+	return nil
+}
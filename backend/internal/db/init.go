@@ -19,11 +19,15 @@ func InitDB(user, password, host, port, dbname string) *gorm.DB {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
 
-	err = db.AutoMigrate(&Streamer{}, &Reward{}, &Block{}, &ConfigStore{}, &User{}, &Request{}, &Moderator{}, &Command{})
+	err = db.AutoMigrate(&Streamer{}, &Reward{}, &Block{}, &ConfigStore{}, &User{}, &Request{}, &Moderator{}, &Command{}, &AllowedPlaylist{}, &RewardConfig{}, &ProcessedEvent{}, &Cooldown{}, &PlayedTrack{}, &Migration{})
 	if err != nil {
 		log.Fatalf("failed to run migrations: %v", err)
 	}
 
+	if err := runOnce(db, "rename_legacy_request_commands", MigrateLegacyRequestCommands); err != nil {
+		log.Printf("Error migrating request commands: %v", err)
+	}
+
 	dbHandle = db
 
 	return db
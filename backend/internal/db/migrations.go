@@ -0,0 +1,32 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// runOnce runs a data migration unless it already ran, and records it once it succeeds
+func runOnce(db *gorm.DB, name string, migrate func(db *gorm.DB) error) error {
+	var migration Migration
+	err := db.Where("migration_name = ?", name).First(&migration).Error
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check migration %s: %w", name, err)
+	}
+
+	if err := migrate(db); err != nil {
+		return err
+	}
+
+	if err := db.Create(&Migration{Name: name, AppliedAt: time.Now()}).Error; err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", name, err)
+	}
+	log.Printf("Applied migration %s", name)
+	return nil
+}
@@ -6,22 +6,23 @@ import (
 
 // Streamer represents the streamer table.
 type Streamer struct {
-	ID              uint          `gorm:"primaryKey;autoIncrement;column:streamer_id"`
-	ChannelID       string        `gorm:"column:streamer_channel_id;size:64;unique;not null"`
-	Name            string        `gorm:"column:streamer_name;size:64;not null"`
-	TwitchToken     string        `gorm:"column:streamer_twitch_token;type:text"`
-	TwitchRefresh   string        `gorm:"column:streamer_twitch_refresh;type:text"`
-	SpotifyToken    string        `gorm:"column:streamer_spotify_token;type:text"`
-	SpotifyRefresh  string        `gorm:"column:streamer_spotify_refresh;type:text"`
-	SpotifyState    string        `gorm:"column:streamer_spotify_state;size:64;unique"`
-	BroadcasterType string        `gorm:"column:broadcaster_type;size:16;default:''"` // "", "affiliate", "partner"
-	UseCommands     bool          `gorm:"column:use_commands;default:true"`           // true for commands, false for rewards
-	Rewards         []Reward      `gorm:"foreignKey:StreamerID"`
-	Blocks          []Block       `gorm:"foreignKey:StreamerID"`
-	ConfigStore     []ConfigStore `gorm:"foreignKey:StreamerID"`
-	Requests        []Request     `gorm:"foreignKey:StreamerID"`
-	Moderators      []Moderator   `gorm:"foreignKey:StreamerID"`
-	Commands        []Command     `gorm:"foreignKey:StreamerID"`
+	ID              uint              `gorm:"primaryKey;autoIncrement;column:streamer_id"`
+	ChannelID       string            `gorm:"column:streamer_channel_id;size:64;unique;not null"`
+	Name            string            `gorm:"column:streamer_name;size:64;not null"`
+	TwitchToken     string            `gorm:"column:streamer_twitch_token;type:text"`
+	TwitchRefresh   string            `gorm:"column:streamer_twitch_refresh;type:text"`
+	SpotifyToken    string            `gorm:"column:streamer_spotify_token;type:text"`
+	SpotifyRefresh  string            `gorm:"column:streamer_spotify_refresh;type:text"`
+	SpotifyState    string            `gorm:"column:streamer_spotify_state;size:64;unique"`
+	BroadcasterType string            `gorm:"column:broadcaster_type;size:16;default:''"` // "", "affiliate", "partner"
+	UseCommands     bool              `gorm:"column:use_commands;default:true"`           // true for commands, false for rewards
+	Rewards         []Reward          `gorm:"foreignKey:StreamerID"`
+	Blocks          []Block           `gorm:"foreignKey:StreamerID"`
+	ConfigStore     []ConfigStore     `gorm:"foreignKey:StreamerID"`
+	Requests        []Request         `gorm:"foreignKey:StreamerID"`
+	Moderators      []Moderator       `gorm:"foreignKey:StreamerID"`
+	Commands        []Command         `gorm:"foreignKey:StreamerID"`
+	Playlists       []AllowedPlaylist `gorm:"foreignKey:StreamerID"`
 }
 
 // Reward represents the rewards table.
@@ -36,11 +37,17 @@ type Reward struct {
 
 // Block represents the blocks table.
 type Block struct {
-	ID         uint   `gorm:"primaryKey;autoIncrement;column:block_id"`
-	StreamerID uint   `gorm:"column:block_streamer_id;not null;index"`
-	SpotifyID  string `gorm:"column:block_spotify_id;size:128;not null"`
-	Type       string `gorm:"column:block_type;size:16;not null"`  // "artist" or "track"
-	Name       string `gorm:"column:block_name;size:256;not null"` // Display name for UI
+	ID         uint       `gorm:"primaryKey;autoIncrement;column:block_id"`
+	StreamerID uint       `gorm:"column:block_streamer_id;not null;index"`
+	SpotifyID  string     `gorm:"column:block_spotify_id;size:128;not null"` // Lowercased name for label and genre blocks, empty for pattern blocks
+	Type       string     `gorm:"column:block_type;size:16;not null"`        // "artist", "track", "album", "label", "genre", "keyword" or "regex"
+	Name       string     `gorm:"column:block_name;size:256;not null"`       // Display name for UI
+	Pattern    string     `gorm:"column:block_pattern;size:512"`             // Keyword or regular expression for pattern blocks
+	ExpiresAt  *time.Time `gorm:"column:block_expires_at;index"`             // Nil for permanent blocks
+	Reason     string     `gorm:"column:block_reason;size:256"`
+	AddedBy    string     `gorm:"column:block_added_by;size:128"` // Twitch name of the moderator or streamer who added the block
+	StreamOnly bool       `gorm:"column:block_stream_only"`       // Removed once the stream goes offline
+	CreatedAt  time.Time  `gorm:"column:block_created_at;autoCreateTime"`
 }
 
 // ConfigStore represents the config_store table.
@@ -48,7 +55,7 @@ type ConfigStore struct {
 	ID         uint   `gorm:"primaryKey;autoIncrement;column:cs_id"`
 	StreamerID uint   `gorm:"column:cs_streamer_id;not null;index"`
 	Key        string `gorm:"column:cs_key;size:128;not null"`
-	Value      string `gorm:"column:cs_value;size:128;not null"`
+	Value      string `gorm:"column:cs_value;size:1024;not null"`
 }
 
 // User represents the users table.
@@ -65,8 +72,15 @@ type Request struct {
 	StreamerID   uint      `gorm:"column:request_streamer_id;not null;index"`
 	UserID       uint      `gorm:"column:request_user_id;not null;index"`
 	SearchPrompt string    `gorm:"column:request_search_prompt;type:text"`
-	TrackID      string    `gorm:"column:request_track_id;size:256"`
+	TrackID      string    `gorm:"column:request_track_id;size:256;index"`
+	TrackName    string    `gorm:"column:request_track_name;size:512"`
+	Source       string    `gorm:"column:request_source;size:16"`       // "reward", "chat" or "web"
+	Status       string    `gorm:"column:request_status;size:16;index"` // "queued", "accepted", "rejected", "played", "skipped" or "refunded"
+	Reason       string    `gorm:"column:request_reason;size:256"`      // Why the request was rejected or refunded
+	RedemptionID string    `gorm:"column:request_redemption_id;size:128;index"`
+	Tier         string    `gorm:"column:request_tier;size:16"` // Priority tier the request was queued with
 	RequestTime  time.Time `gorm:"column:request_time;autoCreateTime"`
+	UpdatedAt    time.Time `gorm:"column:request_updated_at;autoUpdateTime"`
 	// Optional: Associations
 	Streamer Streamer `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
 	User     User     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
@@ -91,6 +105,74 @@ type Command struct {
 	Type       string `gorm:"column:command_type;size:32;not null"` // "request", "block", "volume", etc.
 	Name       string `gorm:"column:command_name;size:32;not null"` // Custom command name
 	IsEnabled  bool   `gorm:"column:command_enabled;default:true"`  // Enable/disable command
+	Permission string `gorm:"column:command_permission;size:16"`    // Required role, empty means the type's default
+	// Optional: Association with Streamer
+	Streamer Streamer `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+}
+
+// AllowedPlaylist represents the allowed_playlists table: playlists whose tracks may be requested in allowlist mode.
+type AllowedPlaylist struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement;column:ap_id"`
+	StreamerID uint      `gorm:"column:ap_streamer_id;not null;index"`
+	PlaylistID string    `gorm:"column:ap_playlist_id;size:64;not null"`
+	Name       string    `gorm:"column:ap_name;size:256"`
+	AddedAt    time.Time `gorm:"column:ap_added_at;autoCreateTime"`
+	// Optional: Association with Streamer
+	Streamer Streamer `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+}
+
+// RewardConfig represents the reward_configs table: the streamer's settings for one bot reward type.
+// Kept apart from Reward so the settings survive rewards being recreated.
+type RewardConfig struct {
+	ID                  uint   `gorm:"primaryKey;autoIncrement;column:rc_id"`
+	StreamerID          uint   `gorm:"column:rc_streamer_id;not null;uniqueIndex:idx_rc_streamer_reward"`
+	InternalID          int8   `gorm:"column:rc_internal_id;not null;uniqueIndex:idx_rc_streamer_reward"`
+	Title               string `gorm:"column:rc_title;size:45;not null"`
+	Cost                int    `gorm:"column:rc_cost;not null"`
+	Prompt              string `gorm:"column:rc_prompt;size:200"`
+	BackgroundColor     string `gorm:"column:rc_background_color;size:7"`
+	GlobalCooldown      int    `gorm:"column:rc_global_cooldown"`         // Seconds, 0 = no cooldown
+	MaxPerStream        int    `gorm:"column:rc_max_per_stream"`          // 0 = unlimited
+	MaxPerUserPerStream int    `gorm:"column:rc_max_per_user_per_stream"` // 0 = unlimited
+	Enabled             *bool  `gorm:"column:rc_enabled"`                 // Nil = the reward type's default
+	// Optional: Association with Streamer
+	Streamer Streamer `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+}
+
+// ProcessedEvent represents the processed_events table: EventSub messages and redemptions that were
+// already handled, so deliveries Twitch retries are not processed twice, even across restarts.
+type ProcessedEvent struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement;column:event_id"`
+	Key       string    `gorm:"column:event_key;size:191;not null;uniqueIndex"` // "<kind>:<id>"
+	ExpiresAt time.Time `gorm:"column:event_expires_at;not null;index"`
+}
+
+// Migration represents the migrations table: data migrations that already ran, so each runs only once
+type Migration struct {
+	Name      string    `gorm:"primaryKey;column:migration_name;size:128"`
+	AppliedAt time.Time `gorm:"column:migration_applied_at;not null"`
+}
+
+// Cooldown represents the cooldowns table: when a track, artist, album or viewer last had a request accepted.
+// The cooldown lengths come from the config, so changing them also applies to running cooldowns.
+type Cooldown struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement;column:cd_id"`
+	StreamerID uint      `gorm:"column:cd_streamer_id;not null;uniqueIndex:idx_cd_streamer_subject"`
+	Kind       string    `gorm:"column:cd_kind;size:16;not null;uniqueIndex:idx_cd_streamer_subject"` // "track", "artist", "album" or "user"
+	Key        string    `gorm:"column:cd_key;size:128;not null;uniqueIndex:idx_cd_streamer_subject"` // Spotify ID, or Twitch ID for viewers
+	Name       string    `gorm:"column:cd_name;size:256"`
+	StartedAt  time.Time `gorm:"column:cd_started_at;not null;index"`
+	// Optional: Association with Streamer
+	Streamer Streamer `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+}
+
+// PlayedTrack represents the played_tracks table: when each track last actually played on a streamer's Spotify.
+type PlayedTrack struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement;column:pt_id"`
+	StreamerID uint      `gorm:"column:pt_streamer_id;not null;uniqueIndex:idx_pt_streamer_track"`
+	TrackID    string    `gorm:"column:pt_track_id;size:64;not null;uniqueIndex:idx_pt_streamer_track"`
+	TrackName  string    `gorm:"column:pt_track_name;size:512"`
+	PlayedAt   time.Time `gorm:"column:pt_played_at;not null;index"`
 	// Optional: Association with Streamer
 	Streamer Streamer `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
 }
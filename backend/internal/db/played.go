@@ -0,0 +1,52 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecordTrackPlayed records that a track played at the given time. Earlier times never replace later ones,
+// so replaying the recently played history is harmless.
+func RecordTrackPlayed(db *gorm.DB, streamerID uint, trackID, trackName string, playedAt time.Time) error {
+	err := db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"pt_track_name": trackName,
+			"pt_played_at":  gorm.Expr("GREATEST(pt_played_at, ?)", playedAt),
+		}),
+	}).Create(&PlayedTrack{
+		StreamerID: streamerID,
+		TrackID:    trackID,
+		TrackName:  trackName,
+		PlayedAt:   playedAt,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to record track %s as played for streamer %d: %w", trackID, streamerID, err)
+	}
+	return nil
+}
+
+// GetPlayedTrack returns when a track last played for a streamer, or nil if it isn't in the ledger
+func GetPlayedTrack(db *gorm.DB, streamerID uint, trackID string) (*PlayedTrack, error) {
+	var played PlayedTrack
+	err := db.Where("pt_streamer_id = ? AND pt_track_id = ?", streamerID, trackID).First(&played).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get played track %s for streamer %d: %w", trackID, streamerID, err)
+	}
+	return &played, nil
+}
+
+// DeletePlayedTracksBefore removes ledger entries of a streamer last played before the given time
+// and returns how many were removed
+func DeletePlayedTracksBefore(db *gorm.DB, streamerID uint, before time.Time) (int64, error) {
+	result := db.Where("pt_streamer_id = ? AND pt_played_at < ?", streamerID, before).Delete(&PlayedTrack{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete played tracks for streamer %d: %w", streamerID, result.Error)
+	}
+	return result.RowsAffected, nil
+}
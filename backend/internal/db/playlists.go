@@ -0,0 +1,54 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// GetAllowedPlaylists returns the allowlisted playlists of a streamer
+func GetAllowedPlaylists(db *gorm.DB, streamerID uint) ([]AllowedPlaylist, error) {
+	var playlists []AllowedPlaylist
+	err := db.Where("ap_streamer_id = ?", streamerID).Order("ap_added_at ASC").Find(&playlists).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allowed playlists for streamer %d: %w", streamerID, err)
+	}
+	return playlists, nil
+}
+
+// AddAllowedPlaylist adds a playlist to a streamer's allowlist, or renames it if it is already there
+func AddAllowedPlaylist(db *gorm.DB, streamerID uint, playlistID, name string) (*AllowedPlaylist, error) {
+	var playlist AllowedPlaylist
+	err := db.Where("ap_streamer_id = ? AND ap_playlist_id = ?", streamerID, playlistID).First(&playlist).Error
+	if err == nil {
+		playlist.Name = name
+		if err := db.Save(&playlist).Error; err != nil {
+			return nil, fmt.Errorf("failed to update allowed playlist %s: %w", playlistID, err)
+		}
+		return &playlist, nil
+	}
+
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	playlist = AllowedPlaylist{
+		StreamerID: streamerID,
+		PlaylistID: playlistID,
+		Name:       name,
+	}
+	if err := db.Create(&playlist).Error; err != nil {
+		return nil, fmt.Errorf("failed to add allowed playlist %s: %w", playlistID, err)
+	}
+	return &playlist, nil
+}
+
+// RemoveAllowedPlaylist removes a playlist from a streamer's allowlist by its row ID.
+// Returns false if the streamer has no such playlist.
+func RemoveAllowedPlaylist(db *gorm.DB, streamerID uint, id uint) (bool, error) {
+	result := db.Where("ap_id = ? AND ap_streamer_id = ?", id, streamerID).Delete(&AllowedPlaylist{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to remove allowed playlist %d: %w", id, result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
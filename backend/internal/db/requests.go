@@ -0,0 +1,199 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Request sources
+const (
+	RequestSourceReward = "reward"
+	RequestSourceChat   = "chat"
+	RequestSourceWeb    = "web"
+)
+
+// Request statuses
+const (
+	RequestStatusQueued   = "queued"   // Waiting in the bot-managed queue
+	RequestStatusAccepted = "accepted" // Handed to Spotify
+	RequestStatusRejected = "rejected"
+	RequestStatusPlayed   = "played"
+	RequestStatusSkipped  = "skipped"
+	RequestStatusRefunded = "refunded"
+)
+
+// quotaRequestStatuses are the statuses of requests that count towards a viewer's quota
+var quotaRequestStatuses = []string{RequestStatusQueued, RequestStatusAccepted, RequestStatusPlayed, RequestStatusSkipped}
+
+// Request priority tiers
+const (
+	PriorityTierBits       = "bits"
+	PriorityTierSubscriber = "subscriber"
+	PriorityTierVIP        = "vip"
+	PriorityTierRegular    = "regular"
+	PriorityTierPlayNext   = "play_next" // Bought with the priority request reward; always plays first and can't be reordered in the tier list
+)
+
+// DefaultPriorityTiers is the default tier order, highest priority first
+var DefaultPriorityTiers = []string{PriorityTierBits, PriorityTierSubscriber, PriorityTierVIP, PriorityTierRegular}
+
+// IsValidPriorityTier checks if a priority tier is known
+func IsValidPriorityTier(tier string) bool {
+	switch tier {
+	case PriorityTierBits, PriorityTierSubscriber, PriorityTierVIP, PriorityTierRegular:
+		return true
+	default:
+		return false
+	}
+}
+
+// UpsertUser creates a user by Twitch ID or updates their display name
+func UpsertUser(db *gorm.DB, twitchID, twitchName string) (*User, error) {
+	var user User
+	err := db.Where("user_twitch_id = ?", twitchID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user = User{
+			TwitchID:   twitchID,
+			TwitchName: twitchName,
+		}
+		if err := db.Create(&user).Error; err != nil {
+			return nil, fmt.Errorf("failed to create user %s: %w", twitchID, err)
+		}
+		return &user, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get user %s: %w", twitchID, err)
+	}
+
+	if user.TwitchName != twitchName && twitchName != "" {
+		user.TwitchName = twitchName
+		if err := db.Save(&user).Error; err != nil {
+			return nil, fmt.Errorf("failed to update user %s: %w", twitchID, err)
+		}
+	}
+
+	return &user, nil
+}
+
+// CreateRequest records a song request
+func CreateRequest(db *gorm.DB, request *Request) error {
+	if err := db.Create(request).Error; err != nil {
+		return fmt.Errorf("failed to create request for streamer %d: %w", request.StreamerID, err)
+	}
+	return nil
+}
+
+// UpdateRequestStatus changes the status of a recorded request
+func UpdateRequestStatus(db *gorm.DB, requestID uint, status, reason string) error {
+	err := db.Model(&Request{}).Where("request_id = ?", requestID).Updates(map[string]interface{}{
+		"request_status": status,
+		"request_reason": reason,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update request %d: %w", requestID, err)
+	}
+	return nil
+}
+
+// GetLatestRequestForTrack returns the most recent request for a track in one of the given statuses
+func GetLatestRequestForTrack(db *gorm.DB, streamerID uint, trackID string, statuses ...string) (*Request, error) {
+	var request Request
+	err := db.Where("request_streamer_id = ? AND request_track_id = ? AND request_status IN ?", streamerID, trackID, statuses).
+		Order("request_time DESC").
+		First(&request).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// MarkTrackPlayed marks the pending request for a track as played
+func MarkTrackPlayed(db *gorm.DB, streamerID uint, trackID string) error {
+	request, err := GetLatestRequestForTrack(db, streamerID, trackID, RequestStatusAccepted)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // Not a requested track
+	} else if err != nil {
+		return err
+	}
+	return UpdateRequestStatus(db, request.ID, RequestStatusPlayed, "")
+}
+
+// MarkTrackSkipped marks the request behind a playing track as skipped
+func MarkTrackSkipped(db *gorm.DB, streamerID uint, trackID, reason string) error {
+	request, err := GetLatestRequestForTrack(db, streamerID, trackID, RequestStatusAccepted, RequestStatusPlayed)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // Not a requested track
+	} else if err != nil {
+		return err
+	}
+	return UpdateRequestStatus(db, request.ID, RequestStatusSkipped, reason)
+}
+
+// GetRequestsByStatus returns a streamer's requests in the given status, oldest first
+func GetRequestsByStatus(db *gorm.DB, streamerID uint, status string) ([]Request, error) {
+	var requests []Request
+	err := db.Preload("User").
+		Where("request_streamer_id = ? AND request_status = ?", streamerID, status).
+		Order("request_time ASC").
+		Find(&requests).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s requests for streamer %d: %w", status, streamerID, err)
+	}
+	return requests, nil
+}
+
+// GetHandledRedemptionIDs returns which of the given redemptions already have an entry in the streamer's request log
+func GetHandledRedemptionIDs(db *gorm.DB, streamerID uint, redemptionIDs []string) (map[string]bool, error) {
+	handled := make(map[string]bool)
+	if len(redemptionIDs) == 0 {
+		return handled, nil
+	}
+
+	var found []string
+	err := db.Model(&Request{}).
+		Where("request_streamer_id = ? AND request_redemption_id IN ?", streamerID, redemptionIDs).
+		Pluck("request_redemption_id", &found).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up redemptions for streamer %d: %w", streamerID, err)
+	}
+
+	for _, id := range found {
+		handled[id] = true
+	}
+	return handled, nil
+}
+
+// userRequests scopes a query to a viewer's requests that count towards their quota
+func userRequests(db *gorm.DB, streamerID uint, twitchID string) *gorm.DB {
+	userIDs := db.Model(&User{}).Select("user_id").Where("user_twitch_id = ?", twitchID)
+	return db.Model(&Request{}).
+		Where("request_streamer_id = ? AND request_user_id IN (?)", streamerID, userIDs).
+		Where("request_status IN ?", quotaRequestStatuses)
+}
+
+// CountUserRequestsSince counts a viewer's requests since the given time, ignoring rejected and refunded ones
+func CountUserRequestsSince(db *gorm.DB, streamerID uint, twitchID string, since time.Time) (int64, error) {
+	var count int64
+	err := userRequests(db, streamerID, twitchID).Where("request_time >= ?", since).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count requests of %s for streamer %d: %w", twitchID, streamerID, err)
+	}
+	return count, nil
+}
+
+// GetRequests returns the request log of a streamer, newest first
+func GetRequests(db *gorm.DB, streamerID uint, status string, limit, offset int) ([]Request, error) {
+	query := db.Preload("User").Where("request_streamer_id = ?", streamerID)
+	if status != "" {
+		query = query.Where("request_status = ?", status)
+	}
+
+	var requests []Request
+	err := query.Order("request_time DESC").Limit(limit).Offset(offset).Find(&requests).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get requests for streamer %d: %w", streamerID, err)
+	}
+	return requests, nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Twitch limits for custom reward settings
+const (
+	MaxRewardTitleLength  = 45
+	MaxRewardPromptLength = 200
+	MaxRewardCooldown     = 7 * 24 * 3600
+)
+
+var rewardColorRegex = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// RewardSettings are the Twitch properties of a bot reward
+type RewardSettings struct {
+	Title               string
+	Cost                int
+	Prompt              string
+	BackgroundColor     string
+	GlobalCooldown      int  // Seconds, 0 = no cooldown
+	MaxPerStream        int  // 0 = unlimited
+	MaxPerUserPerStream int  // 0 = unlimited
+	Enabled             bool // Whether the bot keeps the reward on Twitch
+}
+
+// Validate checks the settings against the Twitch limits
+func (s RewardSettings) Validate() error {
+	if s.Title == "" || utf8.RuneCountInString(s.Title) > MaxRewardTitleLength {
+		return fmt.Errorf("title must be 1-%d characters", MaxRewardTitleLength)
+	}
+	if s.Cost < 1 {
+		return fmt.Errorf("cost must be at least 1")
+	}
+	if utf8.RuneCountInString(s.Prompt) > MaxRewardPromptLength {
+		return fmt.Errorf("prompt must be at most %d characters", MaxRewardPromptLength)
+	}
+	if s.BackgroundColor != "" && !rewardColorRegex.MatchString(s.BackgroundColor) {
+		return fmt.Errorf("background color must be a hex color like #AABBCC")
+	}
+	if s.GlobalCooldown < 0 || s.GlobalCooldown > MaxRewardCooldown {
+		return fmt.Errorf("global cooldown must be between 0 and %d seconds", MaxRewardCooldown)
+	}
+	if s.MaxPerStream < 0 || s.MaxPerUserPerStream < 0 {
+		return fmt.Errorf("redemption limits must not be negative")
+	}
+	return nil
+}
+
+// GetRewardSettings returns the stored settings of a reward type, or the given defaults if none are stored
+func GetRewardSettings(db *gorm.DB, streamerID uint, internalID int8, defaults RewardSettings) RewardSettings {
+	var config RewardConfig
+	err := db.Where("rc_streamer_id = ? AND rc_internal_id = ?", streamerID, internalID).First(&config).Error
+	if err != nil {
+		return defaults
+	}
+
+	settings := RewardSettings{
+		Title:               config.Title,
+		Cost:                config.Cost,
+		Prompt:              config.Prompt,
+		BackgroundColor:     config.BackgroundColor,
+		GlobalCooldown:      config.GlobalCooldown,
+		MaxPerStream:        config.MaxPerStream,
+		MaxPerUserPerStream: config.MaxPerUserPerStream,
+		Enabled:             defaults.Enabled,
+	}
+	if config.Enabled != nil {
+		settings.Enabled = *config.Enabled
+	}
+	return settings
+}
+
+// SetRewardSettings stores the settings of a reward type
+func SetRewardSettings(db *gorm.DB, streamerID uint, internalID int8, settings RewardSettings) error {
+	var config RewardConfig
+	err := db.Where("rc_streamer_id = ? AND rc_internal_id = ?", streamerID, internalID).First(&config).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	config.StreamerID = streamerID
+	config.InternalID = internalID
+	config.Title = settings.Title
+	config.Cost = settings.Cost
+	config.Prompt = settings.Prompt
+	config.BackgroundColor = settings.BackgroundColor
+	config.GlobalCooldown = settings.GlobalCooldown
+	config.MaxPerStream = settings.MaxPerStream
+	config.MaxPerUserPerStream = settings.MaxPerUserPerStream
+	config.Enabled = &settings.Enabled
+
+	if err := db.Save(&config).Error; err != nil {
+		return fmt.Errorf("failed to save settings of reward %d for streamer %d: %w", internalID, streamerID, err)
+	}
+	return nil
+}
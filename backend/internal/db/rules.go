@@ -0,0 +1,55 @@
+package db
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TrackRules are a streamer's metadata requirements for requested tracks. Zero values disable a rule.
+type TrackRules struct {
+	MinSongLength  int // Seconds
+	MinPopularity  int // Spotify popularity, 0-100
+	MinReleaseYear int
+	MaxReleaseYear int
+	BlockedGenres  []string // Lowercase; an artist genre equal to one of them is blocked
+}
+
+// GetTrackRules returns the track metadata rules of a streamer
+func GetTrackRules(db *gorm.DB, streamerID uint) TrackRules {
+	return TrackRules{
+		MinSongLength:  GetConfigInt(db, streamerID, ConfigKeyMinSongLength, 0),
+		MinPopularity:  GetConfigInt(db, streamerID, ConfigKeyMinPopularity, 0),
+		MinReleaseYear: GetConfigInt(db, streamerID, ConfigKeyMinReleaseYear, 0),
+		MaxReleaseYear: GetConfigInt(db, streamerID, ConfigKeyMaxReleaseYear, 0),
+		BlockedGenres:  GetBlockedGenres(db, streamerID),
+	}
+}
+
+// GetBlockedGenres returns the genres whose artists can't be requested
+func GetBlockedGenres(db *gorm.DB, streamerID uint) []string {
+	value, err := GetConfig(db, streamerID, ConfigKeyBlockedGenres)
+	if err != nil {
+		return []string{}
+	}
+	return normalizeGenres(strings.Split(value, ","))
+}
+
+// SetBlockedGenres sets the genres whose artists can't be requested
+func SetBlockedGenres(db *gorm.DB, streamerID uint, genres []string) error {
+	return SetConfig(db, streamerID, ConfigKeyBlockedGenres, strings.Join(normalizeGenres(genres), ","))
+}
+
+// normalizeGenres lowercases and trims genres, dropping empty and repeated ones
+func normalizeGenres(genres []string) []string {
+	result := []string{}
+	seen := make(map[string]bool)
+	for _, genre := range genres {
+		genre = strings.ToLower(strings.TrimSpace(genre))
+		if genre != "" && !seen[genre] {
+			seen[genre] = true
+			result = append(result, genre)
+		}
+	}
+	return result
+}
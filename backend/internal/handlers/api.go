@@ -2,15 +2,19 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/emcifuntik/twitch-spotify-request/internal/db"
 	"github.com/emcifuntik/twitch-spotify-request/internal/twitch"
 	"github.com/gorilla/mux"
+	spotifylib "github.com/zmb3/spotify/v2"
 	"gorm.io/gorm"
 )
 
@@ -24,15 +28,16 @@ type APIResponse struct {
 
 // UserProfileResponse represents user profile data
 type UserProfileResponse struct {
-	ID                uint   `json:"id"`
-	ChannelID         string `json:"channel_id"`
-	Name              string `json:"name"`
-	HasSpotifyLinked  bool   `json:"has_spotify_linked"`
-	HasTwitchLinked   bool   `json:"has_twitch_linked"`
-	RewardsConfigured bool   `json:"rewards_configured"`
-	BroadcasterType   string `json:"broadcaster_type"`
-	UseCommands       bool   `json:"use_commands"`
-	CanUseRewards     bool   `json:"can_use_rewards"`
+	ID                uint     `json:"id"`
+	ChannelID         string   `json:"channel_id"`
+	Name              string   `json:"name"`
+	HasSpotifyLinked  bool     `json:"has_spotify_linked"`
+	HasTwitchLinked   bool     `json:"has_twitch_linked"`
+	RewardsConfigured bool     `json:"rewards_configured"`
+	BroadcasterType   string   `json:"broadcaster_type"`
+	UseCommands       bool     `json:"use_commands"`
+	CanUseRewards     bool     `json:"can_use_rewards"`
+	MissingScopes     []string `json:"missing_scopes,omitempty"` // Twitch scopes the streamer has to log in again for
 }
 
 // QueueResponse represents queue data for API
@@ -42,7 +47,8 @@ type QueueResponse struct {
 	CurrentSongArtists []string     `json:"current_song_artists,omitempty"`
 	Progress           int          `json:"progress"`
 	Duration           int          `json:"duration"`
-	Queue              []QueueTrack `json:"queue"`
+	Queue              []QueueTrack `json:"queue"`    // Spotify's native queue
+	Requests           []QueueTrack `json:"requests"` // Viewer requests waiting in the bot queue
 	Timestamp          int64        `json:"timestamp"`
 }
 
@@ -53,33 +59,160 @@ type QueueTrack struct {
 	Duration int      `json:"duration"`
 	URI      string   `json:"uri"`
 	Image    string   `json:"image,omitempty"`
+
+	// Only set for viewer requests
+	RequestID   uint   `json:"request_id,omitempty"`
+	RequestedBy string `json:"requested_by,omitempty"`
+	Tier        string `json:"tier,omitempty"`
+}
+
+// QueuePositionRequest represents a request to move a queued request
+type QueuePositionRequest struct {
+	Position int `json:"position"`
 }
 
 // SettingsRequest represents a settings update request
 type SettingsRequest struct {
-	MaxSongLength    *int  `json:"max_song_length,omitempty"`
-	CooldownSameSong *int  `json:"cooldown_same_song,omitempty"`
-	WebUIEnabled     *bool `json:"web_ui_enabled,omitempty"`
+	MaxSongLength      *int     `json:"max_song_length,omitempty"`
+	CooldownSameSong   *int     `json:"cooldown_same_song,omitempty"`
+	CooldownSameArtist *int     `json:"cooldown_same_artist,omitempty"` // Seconds, 0 = no cooldown
+	CooldownSameAlbum  *int     `json:"cooldown_same_album,omitempty"`  // Seconds, 0 = no cooldown
+	DuplicateHold      *int     `json:"duplicate_hold,omitempty"`       // Seconds after a track played, 0 = disabled
+	WebUIEnabled       *bool    `json:"web_ui_enabled,omitempty"`
+	PriorityTiers      []string `json:"priority_tiers,omitempty"` // Highest priority first
+	PriorityBitsMin    *int     `json:"priority_bits_min,omitempty"`
+	MaxPendingPerUser  *int     `json:"max_pending_per_user,omitempty"` // 0 = unlimited
+	MaxUserRequests    *int     `json:"max_user_requests,omitempty"`    // Per user_request_window, 0 = unlimited
+	UserRequestWindow  *int     `json:"user_request_window,omitempty"`  // Seconds
+	UserRequestGap     *int     `json:"user_request_gap,omitempty"`     // Seconds between one viewer's requests
+	ExplicitFilter     *string  `json:"explicit_filter,omitempty"`      // "allow", "block" or "subscribers"
+	ExplicitFallback   *bool    `json:"explicit_clean_fallback,omitempty"`
+	MinSongLength      *int     `json:"min_song_length,omitempty"` // Seconds, 0 = no minimum
+	MinPopularity      *int     `json:"min_popularity,omitempty"`  // 0-100
+	MinReleaseYear     *int     `json:"min_release_year,omitempty"`
+	MaxReleaseYear     *int     `json:"max_release_year,omitempty"`
+	BlockedGenres      []string `json:"blocked_genres,omitempty"`       // Whole genre names, matched case-insensitively
+	PlaylistAllowlist  *bool    `json:"playlist_allowlist,omitempty"`   // Only accept tracks from allowed playlists
+	BlockCommandSkip   *bool    `json:"block_command_skip,omitempty"`   // Skip the track blocked with the block chat command
+	VolumeRewardStep   *int     `json:"volume_reward_step,omitempty"`   // Percent the volume rewards change the volume by
+	VolumeRewardMin    *int     `json:"volume_reward_min,omitempty"`    // Lowest volume the volume down reward may set
+	VolumeRewardMax    *int     `json:"volume_reward_max,omitempty"`    // Highest volume the volume up reward may set
+	RequestsClosed     *bool    `json:"requests_closed,omitempty"`      // Refuse all song requests and pause the request rewards
+	MaxQueueLength     *int     `json:"max_queue_length,omitempty"`     // 0 = unlimited
+	MaxQueueDuration   *int     `json:"max_queue_duration,omitempty"`   // Seconds of queued music, 0 = unlimited
+	RedemptionMaxAge   *int     `json:"redemption_max_age,omitempty"`   // Seconds; older missed redemptions are refunded on recovery
+	HeldRequestTimeout *int     `json:"held_request_timeout,omitempty"` // Seconds to wait for a Spotify device, 0 = indefinitely
 }
 
 // SettingsResponse represents current settings
 type SettingsResponse struct {
-	MaxSongLength    int  `json:"max_song_length"`
-	CooldownSameSong int  `json:"cooldown_same_song"`
-	WebUIEnabled     bool `json:"web_ui_enabled"`
+	MaxSongLength      int      `json:"max_song_length"`
+	CooldownSameSong   int      `json:"cooldown_same_song"`
+	CooldownSameArtist int      `json:"cooldown_same_artist"`
+	CooldownSameAlbum  int      `json:"cooldown_same_album"`
+	DuplicateHold      int      `json:"duplicate_hold"`
+	WebUIEnabled       bool     `json:"web_ui_enabled"`
+	PriorityTiers      []string `json:"priority_tiers"`
+	PriorityBitsMin    int      `json:"priority_bits_min"`
+	MaxPendingPerUser  int      `json:"max_pending_per_user"`
+	MaxUserRequests    int      `json:"max_user_requests"`
+	UserRequestWindow  int      `json:"user_request_window"`
+	UserRequestGap     int      `json:"user_request_gap"`
+	ExplicitFilter     string   `json:"explicit_filter"`
+	ExplicitFallback   bool     `json:"explicit_clean_fallback"`
+	MinSongLength      int      `json:"min_song_length"`
+	MinPopularity      int      `json:"min_popularity"`
+	MinReleaseYear     int      `json:"min_release_year"`
+	MaxReleaseYear     int      `json:"max_release_year"`
+	BlockedGenres      []string `json:"blocked_genres"`
+	PlaylistAllowlist  bool     `json:"playlist_allowlist"`
+	BlockCommandSkip   bool     `json:"block_command_skip"`
+	VolumeRewardStep   int      `json:"volume_reward_step"`
+	VolumeRewardMin    int      `json:"volume_reward_min"`
+	VolumeRewardMax    int      `json:"volume_reward_max"`
+	RequestsClosed     bool     `json:"requests_closed"`
+	MaxQueueLength     int      `json:"max_queue_length"`
+	MaxQueueDuration   int      `json:"max_queue_duration"`
+	RedemptionMaxAge   int      `json:"redemption_max_age"`
+	HeldRequestTimeout int      `json:"held_request_timeout"`
 }
 
 // BlockRequest represents a block add/remove request
 type BlockRequest struct {
 	SpotifyID string `json:"spotify_id"`
-	Name      string `json:"name"`
-	Type      string `json:"type"` // "artist" or "track"
+	Name      string `json:"name"`                 // Label names match as whole words of a copyright line, genre names must match a whole genre
+	Type      string `json:"type"`                 // "artist", "track", "album", "label", "genre", "keyword" or "regex"
+	Pattern   string `json:"pattern,omitempty"`    // Keyword or regular expression for pattern blocks
+	ExpiresAt string `json:"expires_at,omitempty"` // RFC 3339; omit for a permanent block
+	Reason    string `json:"reason,omitempty"`
+}
+
+// AllowedPlaylistRequest represents a request to allowlist a playlist
+type AllowedPlaylistRequest struct {
+	Playlist string `json:"playlist"` // Spotify playlist link, URI or ID
+}
+
+// AllowedPlaylistResponse represents an allowlisted playlist
+type AllowedPlaylistResponse struct {
+	ID         uint   `json:"id"`
+	PlaylistID string `json:"playlist_id"`
+	Name       string `json:"name"`
+	AddedAt    string `json:"added_at"`
+}
+
+// RewardSettingsRequest represents a channel-point reward settings update; omitted fields keep their value
+type RewardSettingsRequest struct {
+	Title               *string `json:"title,omitempty"`
+	Cost                *int    `json:"cost,omitempty"`
+	Prompt              *string `json:"prompt,omitempty"`
+	BackgroundColor     *string `json:"background_color,omitempty"`        // "#RRGGBB"
+	GlobalCooldown      *int    `json:"global_cooldown,omitempty"`         // Seconds, 0 = no cooldown
+	MaxPerStream        *int    `json:"max_per_stream,omitempty"`          // 0 = unlimited
+	MaxPerUserPerStream *int    `json:"max_per_user_per_stream,omitempty"` // 0 = unlimited
+	Enabled             *bool   `json:"enabled,omitempty"`                 // Create or delete the reward on Twitch
+}
+
+// RewardResponse represents a channel-point reward and its settings
+type RewardResponse struct {
+	Type                string `json:"type"`
+	TwitchID            string `json:"twitch_id,omitempty"`
+	Title               string `json:"title"`
+	Cost                int    `json:"cost"`
+	Prompt              string `json:"prompt"`
+	BackgroundColor     string `json:"background_color"`
+	GlobalCooldown      int    `json:"global_cooldown"`
+	MaxPerStream        int    `json:"max_per_stream"`
+	MaxPerUserPerStream int    `json:"max_per_user_per_stream"`
+	Enabled             bool   `json:"enabled"`
+}
+
+// DeviceResponse represents a Spotify Connect device of the streamer
+type DeviceResponse struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Active     bool   `json:"active"`
+	Restricted bool   `json:"restricted"`
+	Volume     int    `json:"volume"`
+	Preferred  bool   `json:"preferred"`
+}
+
+// DevicesResponse lists the streamer's devices and the one the bot targets
+type DevicesResponse struct {
+	Devices           []DeviceResponse `json:"devices"`
+	PreferredDeviceID string           `json:"preferred_device_id"` // Empty when the active device is used
+}
+
+// PreferredDeviceRequest represents a request to change the device the bot targets
+type PreferredDeviceRequest struct {
+	DeviceID string `json:"device_id"` // Empty to follow the active device
+	Transfer bool   `json:"transfer"`  // Also move playback to the device
 }
 
 // SpotifySearchRequest represents a Spotify search request
 type SpotifySearchRequest struct {
 	Query string `json:"query"`
-	Type  string `json:"type"` // "artist" or "track"
+	Type  string `json:"type"` // "artist", "track", "album", "label" or "genre"
 	Limit int    `json:"limit,omitempty"`
 }
 
@@ -89,7 +222,7 @@ type SpotifySearchResult struct {
 	Name    string   `json:"name"`
 	Type    string   `json:"type"`
 	Image   string   `json:"image,omitempty"`
-	Artists []string `json:"artists,omitempty"` // For tracks
+	Artists []string `json:"artists,omitempty"` // For tracks and albums
 }
 
 // SpotifySearchResponse represents the response from Spotify search
@@ -121,17 +254,36 @@ type TwitchUserSearchResult struct {
 
 // CommandResponse represents a command for API responses
 type CommandResponse struct {
-	ID        uint   `json:"id"`
-	Type      string `json:"type"`
-	Name      string `json:"name"`
-	IsEnabled bool   `json:"is_enabled"`
+	ID         uint   `json:"id"`
+	Type       string `json:"type"`
+	Name       string `json:"name"`
+	IsEnabled  bool   `json:"is_enabled"`
+	Permission string `json:"permission"`
 }
 
 // CommandRequest represents a command update request
 type CommandRequest struct {
-	Type      string `json:"type"`
-	Name      string `json:"name"`
-	IsEnabled bool   `json:"is_enabled"`
+	Type       string `json:"type"`
+	Name       string `json:"name"`
+	IsEnabled  bool   `json:"is_enabled"`
+	Permission string `json:"permission,omitempty"` // "everyone", "follower", "subscriber", "vip", "moderator", "bot_moderator" or "broadcaster"
+}
+
+// RequestLogEntry represents a recorded song request for API responses
+type RequestLogEntry struct {
+	ID           uint   `json:"id"`
+	UserTwitchID string `json:"user_twitch_id"`
+	UserName     string `json:"user_name"`
+	Prompt       string `json:"prompt"`
+	TrackID      string `json:"track_id,omitempty"`
+	TrackName    string `json:"track_name,omitempty"`
+	Source       string `json:"source"`
+	Status       string `json:"status"`
+	Reason       string `json:"reason,omitempty"`
+	RedemptionID string `json:"redemption_id,omitempty"`
+	Tier         string `json:"tier,omitempty"`
+	RequestedAt  string `json:"requested_at"`
+	UpdatedAt    string `json:"updated_at"`
 }
 
 // RequestModeToggleRequest represents a request to toggle between commands and rewards
@@ -166,9 +318,11 @@ func GetUserProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Check if rewards are actually configured by checking with the reward listener
 	rewardsConfigured := false
+	var missingScopes []string
 	rewardListener := twitch.GetRewardListener(streamer.ChannelID)
 	if rewardListener != nil {
 		rewardsConfigured = rewardListener.CheckRewardsConfigured()
+		missingScopes = rewardListener.MissingScopes()
 	}
 
 	// Check if user can use rewards based on broadcaster type
@@ -188,6 +342,7 @@ func GetUserProfile(w http.ResponseWriter, r *http.Request) {
 		BroadcasterType:   streamer.BroadcasterType,
 		UseCommands:       streamer.UseCommands,
 		CanUseRewards:     canUseRewards,
+		MissingScopes:     missingScopes,
 	}
 
 	writeAPISuccess(w, profile)
@@ -218,56 +373,7 @@ func GetQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert to API format
-	var tracks []QueueTrack
-	for _, track := range queueData.Queue {
-		var artists []string
-		for _, artist := range track.Artists {
-			artists = append(artists, artist.Name)
-		}
-
-		// Get the largest image URL
-		var imageURL string
-		if len(track.Album.Images) > 0 {
-			imageURL = track.Album.Images[0].URL
-		}
-
-		tracks = append(tracks, QueueTrack{
-			Name:     track.Name,
-			Artists:  artists,
-			Duration: int(track.Duration),
-			URI:      string(track.URI),
-			Image:    imageURL,
-		})
-	}
-
-	// Get current track image and artists
-	var currentSongImage string
-	var currentSongArtists []string
-	if queueData.CurrentTrack != nil {
-		if len(queueData.CurrentTrack.Album.Images) > 0 {
-			currentSongImage = queueData.CurrentTrack.Album.Images[0].URL
-		}
-		for _, artist := range queueData.CurrentTrack.Artists {
-			currentSongArtists = append(currentSongArtists, artist.Name)
-		}
-	}
-	var currentSongName string
-	if queueData.CurrentTrack != nil {
-		currentSongName = queueData.CurrentTrack.Name
-	}
-
-	response := QueueResponse{
-		CurrentSong:        currentSongName,
-		CurrentSongImage:   currentSongImage,
-		CurrentSongArtists: currentSongArtists,
-		Progress:           queueData.Progress,
-		Duration:           queueData.Duration,
-		Queue:              tracks,
-		Timestamp:          queueData.LastUpdated,
-	}
-
-	writeAPISuccess(w, response)
+	writeAPISuccess(w, buildQueueResponse(queueData))
 }
 
 // GetPublicQueue returns the queue for public viewing (no auth required)
@@ -316,33 +422,31 @@ func GetPublicQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert to response format
+	writeAPISuccess(w, buildQueueResponse(queueData))
+}
+
+// buildQueueResponse converts listener queue data to the API format
+func buildQueueResponse(queueData *twitch.SongQueueData) QueueResponse {
 	var tracks []QueueTrack
 	for _, track := range queueData.Queue {
-		var artists []string
-		for _, artist := range track.Artists {
-			artists = append(artists, artist.Name)
-		}
-
-		// Get the largest image URL
-		var imageURL string
-		if len(track.Album.Images) > 0 {
-			imageURL = track.Album.Images[0].URL
-		}
+		tracks = append(tracks, trackToQueueTrack(&track))
+	}
 
-		tracks = append(tracks, QueueTrack{
-			Name:     track.Name,
-			Artists:  artists,
-			Duration: int(track.Duration),
-			URI:      string(track.URI),
-			Image:    imageURL,
-		})
+	var requests []QueueTrack
+	for _, request := range queueData.Requests {
+		queueTrack := trackToQueueTrack(request.Track)
+		queueTrack.RequestID = request.RequestID
+		queueTrack.RequestedBy = request.UserName
+		queueTrack.Tier = request.Tier
+		requests = append(requests, queueTrack)
 	}
 
 	// Get current track image and artists
+	var currentSongName string
 	var currentSongImage string
 	var currentSongArtists []string
 	if queueData.CurrentTrack != nil {
+		currentSongName = queueData.CurrentTrack.Name
 		if len(queueData.CurrentTrack.Album.Images) > 0 {
 			currentSongImage = queueData.CurrentTrack.Album.Images[0].URL
 		}
@@ -350,22 +454,39 @@ func GetPublicQueue(w http.ResponseWriter, r *http.Request) {
 			currentSongArtists = append(currentSongArtists, artist.Name)
 		}
 	}
-	var currentSongName string
-	if queueData.CurrentTrack != nil {
-		currentSongName = queueData.CurrentTrack.Name
-	}
 
-	response := QueueResponse{
+	return QueueResponse{
 		CurrentSong:        currentSongName,
 		CurrentSongImage:   currentSongImage,
 		CurrentSongArtists: currentSongArtists,
 		Progress:           queueData.Progress,
 		Duration:           queueData.Duration,
 		Queue:              tracks,
+		Requests:           requests,
 		Timestamp:          queueData.LastUpdated,
 	}
+}
+
+// trackToQueueTrack converts a Spotify track to the API format
+func trackToQueueTrack(track *spotifylib.FullTrack) QueueTrack {
+	var artists []string
+	for _, artist := range track.Artists {
+		artists = append(artists, artist.Name)
+	}
 
-	writeAPISuccess(w, response)
+	// Get the largest image URL
+	var imageURL string
+	if len(track.Album.Images) > 0 {
+		imageURL = track.Album.Images[0].URL
+	}
+
+	return QueueTrack{
+		Name:     track.Name,
+		Artists:  artists,
+		Duration: int(track.Duration),
+		URI:      string(track.URI),
+		Image:    imageURL,
+	}
 }
 
 // UpdateUserSettings updates user settings
@@ -440,6 +561,7 @@ func DebugAPI(w http.ResponseWriter, r *http.Request) {
 		"total_streamers": len(streamers),
 		"database_status": "connected",
 		"api_status":      "working",
+		"eventsub_dedup":  twitch.GetEventDeduplicator().Stats(),
 	}
 
 	writeAPISuccess(w, debugInfo)
@@ -497,13 +619,7 @@ func GetSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	settings := SettingsResponse{
-		MaxSongLength:    db.GetMaxSongLength(database, streamer.ID),
-		CooldownSameSong: db.GetCooldownSameSong(database, streamer.ID),
-		WebUIEnabled:     db.IsWebUIEnabled(database, streamer.ID),
-	}
-
-	writeAPIResponse(w, settings)
+	writeAPIResponse(w, buildSettingsResponse(database, streamer.ID))
 }
 
 // UpdateSettings updates settings for a user
@@ -530,6 +646,101 @@ func UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Per-viewer request limits and track metadata rules
+	limits := []struct {
+		value *int
+		key   string
+	}{
+		{req.MaxPendingPerUser, db.ConfigKeyMaxPendingPerUser},
+		{req.MaxUserRequests, db.ConfigKeyMaxUserRequests},
+		{req.UserRequestWindow, db.ConfigKeyUserRequestWindow},
+		{req.UserRequestGap, db.ConfigKeyUserRequestGap},
+		{req.MinSongLength, db.ConfigKeyMinSongLength},
+		{req.MinPopularity, db.ConfigKeyMinPopularity},
+		{req.MinReleaseYear, db.ConfigKeyMinReleaseYear},
+		{req.MaxReleaseYear, db.ConfigKeyMaxReleaseYear},
+		{req.VolumeRewardStep, db.ConfigKeyVolumeRewardStep},
+		{req.VolumeRewardMin, db.ConfigKeyVolumeRewardMin},
+		{req.VolumeRewardMax, db.ConfigKeyVolumeRewardMax},
+		{req.MaxQueueLength, db.ConfigKeyMaxQueueLength},
+		{req.MaxQueueDuration, db.ConfigKeyMaxQueueDuration},
+		{req.RedemptionMaxAge, db.ConfigKeyRedemptionMaxAge},
+		{req.HeldRequestTimeout, db.ConfigKeyHeldRequestTimeout},
+		{req.CooldownSameArtist, db.ConfigKeyCooldownSameArtist},
+		{req.CooldownSameAlbum, db.ConfigKeyCooldownSameAlbum},
+		{req.DuplicateHold, db.ConfigKeyDuplicateHold},
+	}
+
+	// Validate everything before writing so a rejected request changes nothing
+	for _, limit := range limits {
+		if limit.value != nil && *limit.value < 0 {
+			writeAPIError(w, fmt.Sprintf("%s must not be negative", limit.key), http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, tier := range req.PriorityTiers {
+		if !db.IsValidPriorityTier(tier) {
+			writeAPIError(w, fmt.Sprintf("Invalid priority tier: %s", tier), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.ExplicitFilter != nil && !db.IsValidExplicitFilter(*req.ExplicitFilter) {
+		writeAPIError(w, "Invalid explicit filter: must be allow, block or subscribers", http.StatusBadRequest)
+		return
+	}
+
+	if req.MinPopularity != nil && *req.MinPopularity > 100 {
+		writeAPIError(w, "min_popularity must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+
+	for _, volume := range []*int{req.VolumeRewardStep, req.VolumeRewardMin, req.VolumeRewardMax} {
+		if volume != nil && *volume > 100 {
+			writeAPIError(w, "Volume reward settings must be between 0 and 100", http.StatusBadRequest)
+			return
+		}
+	}
+
+	minVolume, maxVolume := db.GetVolumeRewardBounds(database, streamer.ID)
+	if req.VolumeRewardMin != nil {
+		minVolume = *req.VolumeRewardMin
+	}
+	if req.VolumeRewardMax != nil {
+		maxVolume = *req.VolumeRewardMax
+	}
+	if minVolume > maxVolume {
+		writeAPIError(w, "volume_reward_min must not be above volume_reward_max", http.StatusBadRequest)
+		return
+	}
+
+	// A request cap without a window would never apply
+	maxUserRequests := db.GetMaxUserRequests(database, streamer.ID)
+	if req.MaxUserRequests != nil {
+		maxUserRequests = *req.MaxUserRequests
+	}
+	userRequestWindow := db.GetUserRequestWindow(database, streamer.ID)
+	if req.UserRequestWindow != nil {
+		userRequestWindow = *req.UserRequestWindow
+	}
+	if maxUserRequests > 0 && userRequestWindow == 0 {
+		writeAPIError(w, "user_request_window must be set when max_user_requests is set", http.StatusBadRequest)
+		return
+	}
+
+	rules := db.GetTrackRules(database, streamer.ID)
+	if req.MinReleaseYear != nil {
+		rules.MinReleaseYear = *req.MinReleaseYear
+	}
+	if req.MaxReleaseYear != nil {
+		rules.MaxReleaseYear = *req.MaxReleaseYear
+	}
+	if rules.MinReleaseYear > 0 && rules.MaxReleaseYear > 0 && rules.MinReleaseYear > rules.MaxReleaseYear {
+		writeAPIError(w, "min_release_year must not be above max_release_year", http.StatusBadRequest)
+		return
+	}
+
 	// Update settings if provided
 	if req.MaxSongLength != nil {
 		if err := db.SetConfigInt(database, streamer.ID, db.ConfigKeyMaxSongLength, *req.MaxSongLength); err != nil {
@@ -552,14 +763,115 @@ func UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Return updated settings
-	settings := SettingsResponse{
-		MaxSongLength:    db.GetMaxSongLength(database, streamer.ID),
-		CooldownSameSong: db.GetCooldownSameSong(database, streamer.ID),
-		WebUIEnabled:     db.IsWebUIEnabled(database, streamer.ID),
+	if req.PriorityTiers != nil {
+		if err := db.SetPriorityTiers(database, streamer.ID, req.PriorityTiers); err != nil {
+			writeAPIError(w, "Failed to update priority tiers", http.StatusInternalServerError)
+			return
+		}
 	}
 
-	writeAPIResponse(w, settings)
+	if req.PriorityBitsMin != nil {
+		if err := db.SetConfigInt(database, streamer.ID, db.ConfigKeyPriorityBitsMin, *req.PriorityBitsMin); err != nil {
+			writeAPIError(w, "Failed to update priority bits minimum", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.ExplicitFilter != nil {
+		if err := db.SetConfig(database, streamer.ID, db.ConfigKeyExplicitFilter, *req.ExplicitFilter); err != nil {
+			writeAPIError(w, "Failed to update explicit filter", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.ExplicitFallback != nil {
+		if err := db.SetConfigBool(database, streamer.ID, db.ConfigKeyExplicitFallback, *req.ExplicitFallback); err != nil {
+			writeAPIError(w, "Failed to update explicit fallback setting", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.PlaylistAllowlist != nil {
+		if err := db.SetConfigBool(database, streamer.ID, db.ConfigKeyPlaylistAllowlist, *req.PlaylistAllowlist); err != nil {
+			writeAPIError(w, "Failed to update playlist allowlist setting", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.RequestsClosed != nil {
+		if err := db.SetConfigBool(database, streamer.ID, db.ConfigKeyRequestsClosed, *req.RequestsClosed); err != nil {
+			writeAPIError(w, "Failed to update requests closed setting", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.BlockCommandSkip != nil {
+		if err := db.SetConfigBool(database, streamer.ID, db.ConfigKeyBlockCommandSkip, *req.BlockCommandSkip); err != nil {
+			writeAPIError(w, "Failed to update block command skip setting", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.BlockedGenres != nil {
+		if err := db.SetBlockedGenres(database, streamer.ID, req.BlockedGenres); err != nil {
+			writeAPIError(w, "Failed to update blocked genres", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for _, limit := range limits {
+		if limit.value == nil {
+			continue
+		}
+		if err := db.SetConfigInt(database, streamer.ID, limit.key, *limit.value); err != nil {
+			writeAPIError(w, fmt.Sprintf("Failed to update %s", limit.key), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// The requests switch and queue limits decide which rewards are paused
+	if rl := twitch.GetRewardListener(streamer.ChannelID); rl != nil {
+		go rl.UpdateRewardPauses()
+	}
+
+	writeAPIResponse(w, buildSettingsResponse(database, streamer.ID))
+}
+
+// buildSettingsResponse collects the current settings of a streamer
+func buildSettingsResponse(database *gorm.DB, streamerID uint) SettingsResponse {
+	rules := db.GetTrackRules(database, streamerID)
+	volumeMin, volumeMax := db.GetVolumeRewardBounds(database, streamerID)
+	return SettingsResponse{
+		MaxSongLength:      db.GetMaxSongLength(database, streamerID),
+		CooldownSameSong:   db.GetCooldownSameSong(database, streamerID),
+		CooldownSameArtist: db.GetCooldownSameArtist(database, streamerID),
+		CooldownSameAlbum:  db.GetCooldownSameAlbum(database, streamerID),
+		DuplicateHold:      db.GetDuplicateHold(database, streamerID),
+		WebUIEnabled:       db.IsWebUIEnabled(database, streamerID),
+		PriorityTiers:      db.GetPriorityTiers(database, streamerID),
+		PriorityBitsMin:    db.GetPriorityBitsMin(database, streamerID),
+		MaxPendingPerUser:  db.GetMaxPendingPerUser(database, streamerID),
+		MaxUserRequests:    db.GetMaxUserRequests(database, streamerID),
+		UserRequestWindow:  db.GetUserRequestWindow(database, streamerID),
+		UserRequestGap:     db.GetUserRequestGap(database, streamerID),
+		ExplicitFilter:     db.GetExplicitFilter(database, streamerID),
+		ExplicitFallback:   db.IsExplicitFallbackEnabled(database, streamerID),
+		MinSongLength:      rules.MinSongLength,
+		MinPopularity:      rules.MinPopularity,
+		MinReleaseYear:     rules.MinReleaseYear,
+		MaxReleaseYear:     rules.MaxReleaseYear,
+		BlockedGenres:      rules.BlockedGenres,
+		PlaylistAllowlist:  db.IsPlaylistAllowlistEnabled(database, streamerID),
+		BlockCommandSkip:   db.IsBlockCommandSkipEnabled(database, streamerID),
+		VolumeRewardStep:   db.GetVolumeRewardStep(database, streamerID),
+		VolumeRewardMin:    volumeMin,
+		VolumeRewardMax:    volumeMax,
+		RequestsClosed:     db.AreRequestsClosed(database, streamerID),
+		MaxQueueLength:     db.GetMaxQueueLength(database, streamerID),
+		MaxQueueDuration:   db.GetMaxQueueDuration(database, streamerID),
+		RedemptionMaxAge:   db.GetRedemptionMaxAge(database, streamerID),
+		HeldRequestTimeout: db.GetHeldRequestTimeout(database, streamerID),
+	}
 }
 
 // GetBlocks returns the blocklist for a user
@@ -600,11 +912,44 @@ func AddBlock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.SpotifyID == "" || req.Name == "" {
+	blockType := db.BlockType(req.Type)
+	if db.IsPatternBlockType(blockType) {
+		if err := db.ValidateBlockPattern(blockType, req.Pattern); err != nil {
+			writeAPIError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if db.IsNameBlockType(blockType) {
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			writeAPIError(w, "Name is required", http.StatusBadRequest)
+			return
+		}
+	} else if req.SpotifyID == "" || req.Name == "" {
 		writeAPIError(w, "Spotify ID and name are required", http.StatusBadRequest)
 		return
 	}
 
+	opts := db.BlockOptions{Reason: strings.TrimSpace(req.Reason)}
+	if len(opts.Reason) > 256 {
+		writeAPIError(w, "Reason must be at most 256 characters", http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			writeAPIError(w, "expires_at must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		if !expiresAt.After(time.Now()) {
+			writeAPIError(w, "expires_at must be in the future", http.StatusBadRequest)
+			return
+		}
+		opts.ExpiresAt = &expiresAt
+	}
+	if claims, ok := GetClaimsFromContext(r); ok {
+		opts.AddedBy = claims.Username
+	}
+
 	database := db.GetDB()
 	if database == nil {
 		writeAPIError(w, "Database connection error", http.StatusInternalServerError)
@@ -619,12 +964,18 @@ func AddBlock(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Add block
-	blockType := db.BlockTypeTrack
-	if req.Type == "artist" {
-		blockType = db.BlockTypeArtist
+	var err error
+	switch blockType {
+	case db.BlockTypeKeyword, db.BlockTypeRegex:
+		err = db.AddPatternBlock(database, streamer.ID, blockType, req.Pattern, opts)
+	case db.BlockTypeLabel, db.BlockTypeGenre:
+		err = db.AddBlock(database, streamer.ID, blockType, strings.ToLower(req.Name), req.Name, opts)
+	case db.BlockTypeArtist, db.BlockTypeAlbum:
+		err = db.AddBlock(database, streamer.ID, blockType, req.SpotifyID, req.Name, opts)
+	default:
+		err = db.AddBlock(database, streamer.ID, db.BlockTypeTrack, req.SpotifyID, req.Name, opts)
 	}
-
-	if err := db.AddBlock(database, streamer.ID, blockType, req.SpotifyID, req.Name); err != nil {
+	if err != nil {
 		writeAPIError(w, "Failed to add block", http.StatusInternalServerError)
 		return
 	}
@@ -672,7 +1023,7 @@ func RemoveBlock(w http.ResponseWriter, r *http.Request) {
 	writeAPIResponse(w, map[string]string{"message": "Block removed successfully"})
 }
 
-// SpotifySearch searches Spotify for artists or tracks for autocomplete
+// SpotifySearch searches Spotify for artists, tracks, albums, labels or genres for autocomplete
 func SpotifySearch(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID := vars["userID"]
@@ -690,6 +1041,17 @@ func SpotifySearch(w http.ResponseWriter, r *http.Request) {
 		searchType = "artist,track"
 	}
 
+	wanted := make(map[string]bool)
+	for _, t := range strings.Split(searchType, ",") {
+		switch t = strings.TrimSpace(t); t {
+		case "artist", "track", "album", "label", "genre":
+			wanted[t] = true
+		default:
+			writeAPIError(w, fmt.Sprintf("Unsupported search type '%s'", t), http.StatusBadRequest)
+			return
+		}
+	}
+
 	limit := 10
 	if limitStr != "" {
 		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 50 {
@@ -715,110 +1077,211 @@ func SpotifySearch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Make request to Spotify Web API
+	// Genres have no search of their own, they are collected from matching artists
+	var apiTypes []string
+	for _, t := range []string{"artist", "track", "album"} {
+		if wanted[t] || (t == "artist" && wanted["genre"]) {
+			apiTypes = append(apiTypes, t)
+		}
+	}
+
+	var results []SpotifySearchResult
+
+	if len(apiTypes) > 0 {
+		spotifyResp, err := searchSpotifyAPI(streamer.SpotifyToken, query, strings.Join(apiTypes, ","), limit)
+		if err != nil {
+			writeSpotifySearchError(w, err)
+			return
+		}
+
+		// Process artists
+		if wanted["artist"] {
+			for _, artist := range spotifyResp.Artists.Items {
+				result := SpotifySearchResult{
+					ID:   artist.ID,
+					Name: artist.Name,
+					Type: "artist",
+				}
+				if len(artist.Images) > 0 {
+					result.Image = artist.Images[0].URL
+				}
+				results = append(results, result)
+			}
+		}
+
+		// Process tracks
+		for _, track := range spotifyResp.Tracks.Items {
+			result := SpotifySearchResult{
+				ID:   track.ID,
+				Name: track.Name,
+				Type: "track",
+			}
+
+			// Add artist names
+			for _, artist := range track.Artists {
+				result.Artists = append(result.Artists, artist.Name)
+			}
+
+			// Add album image
+			if len(track.Album.Images) > 0 {
+				result.Image = track.Album.Images[0].URL
+			}
+
+			results = append(results, result)
+		}
+
+		// Process albums
+		for _, album := range spotifyResp.Albums.Items {
+			result := SpotifySearchResult{
+				ID:   album.ID,
+				Name: album.Name,
+				Type: "album",
+			}
+			for _, artist := range album.Artists {
+				result.Artists = append(result.Artists, artist.Name)
+			}
+			if len(album.Images) > 0 {
+				result.Image = album.Images[0].URL
+			}
+			results = append(results, result)
+		}
+
+		// Collect genres of the matching artists that contain the query
+		if wanted["genre"] {
+			needle := strings.ToLower(query)
+			seen := make(map[string]bool)
+			for _, artist := range spotifyResp.Artists.Items {
+				for _, genre := range artist.Genres {
+					genre = strings.ToLower(genre)
+					if seen[genre] || !strings.Contains(genre, needle) {
+						continue
+					}
+					seen[genre] = true
+					results = append(results, SpotifySearchResult{
+						ID:   genre,
+						Name: genre,
+						Type: "genre",
+					})
+				}
+			}
+		}
+	}
+
+	// Labels have no search of their own either; the query is offered as a label if Spotify has albums under it
+	if wanted["label"] {
+		labelResp, err := searchSpotifyAPI(streamer.SpotifyToken, fmt.Sprintf("label:\"%s\"", query), "album", 1)
+		if err != nil {
+			writeSpotifySearchError(w, err)
+			return
+		}
+		if len(labelResp.Albums.Items) > 0 {
+			result := SpotifySearchResult{
+				ID:   strings.ToLower(query),
+				Name: query,
+				Type: "label",
+			}
+			if images := labelResp.Albums.Items[0].Images; len(images) > 0 {
+				result.Image = images[0].URL
+			}
+			results = append(results, result)
+		}
+	}
+
+	response := SpotifySearchResponse{
+		Results: results,
+	}
+
+	writeAPIResponse(w, response)
+}
+
+// spotifySearchImage is an image in a Spotify search response
+type spotifySearchImage struct {
+	URL string `json:"url"`
+}
+
+// spotifySearchPayload is the part of a Spotify search response used for autocomplete
+type spotifySearchPayload struct {
+	Artists struct {
+		Items []struct {
+			ID     string               `json:"id"`
+			Name   string               `json:"name"`
+			Genres []string             `json:"genres"`
+			Images []spotifySearchImage `json:"images"`
+		} `json:"items"`
+	} `json:"artists"`
+	Tracks struct {
+		Items []struct {
+			ID      string `json:"id"`
+			Name    string `json:"name"`
+			Artists []struct {
+				Name string `json:"name"`
+			} `json:"artists"`
+			Album struct {
+				Images []spotifySearchImage `json:"images"`
+			} `json:"album"`
+		} `json:"items"`
+	} `json:"tracks"`
+	Albums struct {
+		Items []struct {
+			ID      string `json:"id"`
+			Name    string `json:"name"`
+			Artists []struct {
+				Name string `json:"name"`
+			} `json:"artists"`
+			Images []spotifySearchImage `json:"images"`
+		} `json:"items"`
+	} `json:"albums"`
+}
+
+// errSpotifyTokenExpired is returned by searchSpotifyAPI when Spotify rejects the streamer's token
+var errSpotifyTokenExpired = errors.New("spotify token expired")
+
+// searchSpotifyAPI calls the Spotify search endpoint with the streamer's token
+func searchSpotifyAPI(token, query, searchType string, limit int) (*spotifySearchPayload, error) {
 	spotifyURL := fmt.Sprintf("https://api.spotify.com/v1/search?q=%s&type=%s&limit=%d",
 		url.QueryEscape(query), searchType, limit)
 
 	req, err := http.NewRequest("GET", spotifyURL, nil)
 	if err != nil {
-		writeAPIError(w, "Failed to create request", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+streamer.SpotifyToken)
+	req.Header.Set("Authorization", "Bearer "+token)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		writeAPIError(w, "Failed to search Spotify", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("failed to search Spotify: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 401 {
 		// Token might be expired, try to refresh
 		// TODO: Implement token refresh logic here
-		writeAPIError(w, "Spotify token expired", http.StatusUnauthorized)
-		return
+		return nil, errSpotifyTokenExpired
 	}
 
 	if resp.StatusCode != 200 {
-		writeAPIError(w, "Spotify API error", http.StatusInternalServerError)
-		return
-	}
-
-	var spotifyResp struct {
-		Artists struct {
-			Items []struct {
-				ID     string `json:"id"`
-				Name   string `json:"name"`
-				Images []struct {
-					URL string `json:"url"`
-				} `json:"images"`
-			} `json:"items"`
-		} `json:"artists"`
-		Tracks struct {
-			Items []struct {
-				ID      string `json:"id"`
-				Name    string `json:"name"`
-				Artists []struct {
-					Name string `json:"name"`
-				} `json:"artists"`
-				Album struct {
-					Images []struct {
-						URL string `json:"url"`
-					} `json:"images"`
-				} `json:"album"`
-			} `json:"items"`
-		} `json:"tracks"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&spotifyResp); err != nil {
-		writeAPIError(w, "Failed to parse Spotify response", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("spotify API error: status %d", resp.StatusCode)
 	}
 
-	var results []SpotifySearchResult
-
-	// Process artists
-	for _, artist := range spotifyResp.Artists.Items {
-		result := SpotifySearchResult{
-			ID:   artist.ID,
-			Name: artist.Name,
-			Type: "artist",
-		}
-		if len(artist.Images) > 0 {
-			result.Image = artist.Images[0].URL
-		}
-		results = append(results, result)
+	var payload spotifySearchPayload
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to parse Spotify response: %w", err)
 	}
 
-	// Process tracks
-	for _, track := range spotifyResp.Tracks.Items {
-		result := SpotifySearchResult{
-			ID:   track.ID,
-			Name: track.Name,
-			Type: "track",
-		}
-
-		// Add artist names
-		for _, artist := range track.Artists {
-			result.Artists = append(result.Artists, artist.Name)
-		}
-
-		// Add album image
-		if len(track.Album.Images) > 0 {
-			result.Image = track.Album.Images[0].URL
-		}
-
-		results = append(results, result)
-	}
+	return &payload, nil
+}
 
-	response := SpotifySearchResponse{
-		Results: results,
+// writeSpotifySearchError maps a searchSpotifyAPI error to an API error response
+func writeSpotifySearchError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errSpotifyTokenExpired) {
+		writeAPIError(w, "Spotify token expired", http.StatusUnauthorized)
+		return
 	}
 
-	writeAPIResponse(w, response)
+	log.Printf("Error searching Spotify: %v", err)
+	writeAPIError(w, "Failed to search Spotify", http.StatusInternalServerError)
 }
 
 // FixRewards attempts to fix/recreate rewards for a user
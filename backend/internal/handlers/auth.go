@@ -29,7 +29,7 @@ func init() {
 		ClientID:     os.Getenv("TWITCH_CLIENT_ID"),
 		ClientSecret: os.Getenv("TWITCH_CLIENT_SECRET"),
 		RedirectURL:  botHost + "oauth/twitch",
-		Scopes:       []string{"user:read:chat", "user:write:chat", "channel:bot", "user:bot", "channel:read:redemptions", "channel:manage:redemptions"},
+		Scopes:       []string{"user:read:chat", "user:write:chat", "channel:bot", "user:bot", "channel:read:redemptions", "channel:manage:redemptions", "moderator:read:followers", "channel:read:subscriptions", "channel:read:vips"},
 		Endpoint: oauth2.Endpoint{
 			AuthURL:  "https://id.twitch.tv/oauth2/authorize",
 			TokenURL: "https://id.twitch.tv/oauth2/token",
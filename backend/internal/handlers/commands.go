@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"github.com/emcifuntik/twitch-spotify-request/internal/twitch"
 	"github.com/gorilla/mux"
 )
 
@@ -45,10 +46,11 @@ func GetCommands(w http.ResponseWriter, r *http.Request) {
 	var response []CommandResponse
 	for _, cmd := range commands {
 		response = append(response, CommandResponse{
-			ID:        cmd.ID,
-			Type:      cmd.Type,
-			Name:      cmd.Name,
-			IsEnabled: cmd.IsEnabled,
+			ID:         cmd.ID,
+			Type:       cmd.Type,
+			Name:       cmd.Name,
+			IsEnabled:  cmd.IsEnabled,
+			Permission: cmd.EffectivePermission(),
 		})
 	}
 
@@ -76,6 +78,16 @@ func UpdateCommand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Permission != "" && !db.IsValidPermission(req.Permission) {
+		writeAPIError(w, "Invalid permission level", http.StatusBadRequest)
+		return
+	}
+
+	if twitch.IsReservedCommandName(req.Name, req.Type) {
+		writeAPIError(w, "Command name is taken by a built-in command", http.StatusBadRequest)
+		return
+	}
+
 	database := db.GetDB()
 	if database == nil {
 		writeAPIError(w, "Database not available", http.StatusInternalServerError)
@@ -91,7 +103,7 @@ func UpdateCommand(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update command
-	err := db.CreateOrUpdateCommand(database, streamer.ID, req.Type, req.Name, req.IsEnabled)
+	err := db.CreateOrUpdateCommand(database, streamer.ID, req.Type, req.Name, req.IsEnabled, req.Permission)
 	if err != nil {
 		log.Printf("Error updating command for user %s: %v", userID, err)
 		writeAPIError(w, "Failed to update command", http.StatusInternalServerError)
@@ -146,6 +158,11 @@ func ToggleRequestMode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Keep the running listener in sync so chat requests switch immediately
+	if rl := twitch.GetRewardListener(userID); rl != nil {
+		rl.SetUseCommands(req.UseCommands)
+	}
+
 	writeAPISuccess(w, map[string]string{"message": "Request mode updated successfully"})
 }
 
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/twitch"
+	"github.com/gorilla/mux"
+)
+
+// GetDevices returns the Spotify Connect devices of a user and the one the bot targets
+func GetDevices(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+
+	rewardListener := twitch.GetRewardListener(userID)
+	if rewardListener == nil {
+		writeAPIError(w, "User not found or not active", http.StatusNotFound)
+		return
+	}
+
+	devices, preferredID, err := rewardListener.Devices()
+	if err != nil {
+		log.Printf("Error getting Spotify devices for user %s: %v", userID, err)
+		writeAPIError(w, "Failed to get Spotify devices", http.StatusInternalServerError)
+		return
+	}
+
+	response := DevicesResponse{
+		Devices:           []DeviceResponse{},
+		PreferredDeviceID: preferredID,
+	}
+	for _, device := range devices {
+		response.Devices = append(response.Devices, DeviceResponse{
+			ID:         string(device.ID),
+			Name:       device.Name,
+			Type:       device.Type,
+			Active:     device.Active,
+			Restricted: device.Restricted,
+			Volume:     int(device.Volume),
+			Preferred:  preferredID != "" && string(device.ID) == preferredID,
+		})
+	}
+
+	writeAPISuccess(w, response)
+}
+
+// SetPreferredDevice changes the Spotify device the bot targets and optionally moves playback to it
+func SetPreferredDevice(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+
+	var req PreferredDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	rewardListener := twitch.GetRewardListener(userID)
+	if rewardListener == nil {
+		writeAPIError(w, "User not found or not active", http.StatusNotFound)
+		return
+	}
+
+	if err := rewardListener.SetPreferredDevice(req.DeviceID, req.Transfer); err != nil {
+		if errors.Is(err, twitch.ErrUnknownDevice) {
+			writeAPIError(w, "Device not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error setting preferred device for user %s: %v", userID, err)
+		writeAPIError(w, "Failed to set preferred device", http.StatusInternalServerError)
+		return
+	}
+
+	writeAPIResponse(w, map[string]string{"message": "Preferred device updated", "preferred_device_id": req.DeviceID})
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"github.com/emcifuntik/twitch-spotify-request/internal/spotify"
+	"github.com/emcifuntik/twitch-spotify-request/internal/twitch"
+	"github.com/gorilla/mux"
+)
+
+// spotifyIDRegex matches a bare Spotify ID
+var spotifyIDRegex = regexp.MustCompile(`^[0-9A-Za-z]{22}$`)
+
+// GetAllowedPlaylists returns the allowlisted playlists of a user
+func GetAllowedPlaylists(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+
+	database := db.GetDB()
+	if database == nil {
+		writeAPIError(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+
+	// Get streamer by channel ID (userID)
+	var streamer db.Streamer
+	if err := database.Where("streamer_channel_id = ?", userID).First(&streamer).Error; err != nil {
+		writeAPIError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	playlists, err := db.GetAllowedPlaylists(database, streamer.ID)
+	if err != nil {
+		log.Printf("Error getting allowed playlists for user %s: %v", userID, err)
+		writeAPIError(w, "Failed to get playlists", http.StatusInternalServerError)
+		return
+	}
+
+	response := []AllowedPlaylistResponse{}
+	for _, playlist := range playlists {
+		response = append(response, toAllowedPlaylistResponse(&playlist))
+	}
+
+	writeAPIResponse(w, response)
+}
+
+// AddAllowedPlaylist adds a playlist to a user's allowlist
+func AddAllowedPlaylist(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+
+	var req AllowedPlaylistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	playlistID := strings.TrimSpace(req.Playlist)
+	if link := spotify.FindSpotifyLink(playlistID); link != nil {
+		if link.Type != spotify.LinkTypePlaylist {
+			writeAPIError(w, "Link does not point to a playlist", http.StatusBadRequest)
+			return
+		}
+		playlistID = link.ID
+	}
+	if !spotifyIDRegex.MatchString(playlistID) {
+		writeAPIError(w, "Invalid playlist link or ID", http.StatusBadRequest)
+		return
+	}
+
+	database := db.GetDB()
+	if database == nil {
+		writeAPIError(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+
+	// Get streamer by channel ID (userID)
+	var streamer db.Streamer
+	if err := database.Where("streamer_channel_id = ?", userID).First(&streamer).Error; err != nil {
+		writeAPIError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	rewardListener := twitch.GetRewardListener(userID)
+	if rewardListener == nil {
+		writeAPIError(w, "User not found or not active", http.StatusNotFound)
+		return
+	}
+
+	// Loading the playlist also checks that the streamer's account can read it
+	name, err := rewardListener.GetPlaylistName(playlistID)
+	if err != nil {
+		log.Printf("Error loading playlist %s for user %s: %v", playlistID, userID, err)
+		writeAPIError(w, "Playlist not found or not accessible", http.StatusBadRequest)
+		return
+	}
+
+	playlist, err := db.AddAllowedPlaylist(database, streamer.ID, playlistID, name)
+	if err != nil {
+		log.Printf("Error adding allowed playlist for user %s: %v", userID, err)
+		writeAPIError(w, "Failed to add playlist", http.StatusInternalServerError)
+		return
+	}
+
+	writeAPIResponse(w, toAllowedPlaylistResponse(playlist))
+}
+
+// RemoveAllowedPlaylist removes a playlist from a user's allowlist
+func RemoveAllowedPlaylist(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+
+	id, err := strconv.ParseUint(vars["playlistID"], 10, 32)
+	if err != nil {
+		writeAPIError(w, "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	database := db.GetDB()
+	if database == nil {
+		writeAPIError(w, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+
+	// Get streamer by channel ID (userID)
+	var streamer db.Streamer
+	if err := database.Where("streamer_channel_id = ?", userID).First(&streamer).Error; err != nil {
+		writeAPIError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	removed, err := db.RemoveAllowedPlaylist(database, streamer.ID, uint(id))
+	if err != nil {
+		log.Printf("Error removing allowed playlist for user %s: %v", userID, err)
+		writeAPIError(w, "Failed to remove playlist", http.StatusInternalServerError)
+		return
+	}
+
+	if !removed {
+		writeAPIError(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	writeAPIResponse(w, map[string]string{"message": "Playlist removed successfully"})
+}
+
+// toAllowedPlaylistResponse converts an allowlisted playlist to the API format
+func toAllowedPlaylistResponse(playlist *db.AllowedPlaylist) AllowedPlaylistResponse {
+	return AllowedPlaylistResponse{
+		ID:         playlist.ID,
+		PlaylistID: playlist.PlaylistID,
+		Name:       playlist.Name,
+		AddedAt:    playlist.AddedAt.Format("2006-01-02 15:04:05"),
+	}
+}
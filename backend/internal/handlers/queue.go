@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/twitch"
+	"github.com/gorilla/mux"
+)
+
+// RemoveQueuedRequest removes a viewer request from the bot queue and refunds it
+func RemoveQueuedRequest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+
+	requestID, err := strconv.ParseUint(vars["requestID"], 10, 32)
+	if err != nil {
+		writeAPIError(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	rewardListener := twitch.GetRewardListener(userID)
+	if rewardListener == nil {
+		writeAPIError(w, "User not found or not active", http.StatusNotFound)
+		return
+	}
+
+	if !rewardListener.RemoveQueuedRequest(uint(requestID), "removed from queue by streamer") {
+		writeAPIError(w, "Request not found in queue", http.StatusNotFound)
+		return
+	}
+
+	writeAPIResponse(w, map[string]string{"message": "Request removed from queue"})
+}
+
+// MoveQueuedRequest moves a viewer request to a new position in the bot queue
+func MoveQueuedRequest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+
+	requestID, err := strconv.ParseUint(vars["requestID"], 10, 32)
+	if err != nil {
+		writeAPIError(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	var req QueuePositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Position < 1 {
+		writeAPIError(w, "Position must be at least 1", http.StatusBadRequest)
+		return
+	}
+
+	rewardListener := twitch.GetRewardListener(userID)
+	if rewardListener == nil {
+		writeAPIError(w, "User not found or not active", http.StatusNotFound)
+		return
+	}
+
+	if !rewardListener.MoveQueuedRequest(uint(requestID), req.Position) {
+		writeAPIError(w, "Request not found in queue", http.StatusNotFound)
+		return
+	}
+
+	writeAPIResponse(w, map[string]string{"message": "Request moved"})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"github.com/gorilla/mux"
+)
+
+// GetRequestLog returns the recorded song requests for a user, newest first
+func GetRequestLog(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+
+	if userID == "" {
+		writeAPIError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 200 {
+			limit = parsedLimit
+		}
+	}
+
+	offset := 0
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	status := r.URL.Query().Get("status")
+
+	database := db.GetDB()
+	if database == nil {
+		writeAPIError(w, "Database not available", http.StatusInternalServerError)
+		return
+	}
+
+	var streamer db.Streamer
+	result := database.Where("streamer_channel_id = ?", userID).First(&streamer)
+	if result.Error != nil {
+		writeAPIError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	requests, err := db.GetRequests(database, streamer.ID, status, limit, offset)
+	if err != nil {
+		log.Printf("Error getting request log for user %s: %v", userID, err)
+		writeAPIError(w, "Failed to get requests", http.StatusInternalServerError)
+		return
+	}
+
+	// Convert to response format
+	var response []RequestLogEntry
+	for _, request := range requests {
+		response = append(response, RequestLogEntry{
+			ID:           request.ID,
+			UserTwitchID: request.User.TwitchID,
+			UserName:     request.User.TwitchName,
+			Prompt:       request.SearchPrompt,
+			TrackID:      request.TrackID,
+			TrackName:    request.TrackName,
+			Source:       request.Source,
+			Status:       request.Status,
+			Reason:       request.Reason,
+			RedemptionID: request.RedemptionID,
+			Tier:         request.Tier,
+			RequestedAt:  request.RequestTime.Format("2006-01-02 15:04:05"),
+			UpdatedAt:    request.UpdatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	writeAPISuccess(w, response)
+}
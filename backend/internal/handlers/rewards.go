@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/twitch"
+	"github.com/gorilla/mux"
+)
+
+// GetRewards returns the channel-point rewards of a user with their settings
+func GetRewards(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+
+	rewardListener := twitch.GetRewardListener(userID)
+	if rewardListener == nil {
+		writeAPIError(w, "User not found or not active", http.StatusNotFound)
+		return
+	}
+
+	var response []RewardResponse
+	for _, reward := range rewardListener.Rewards() {
+		response = append(response, rewardToResponse(reward))
+	}
+
+	writeAPISuccess(w, response)
+}
+
+// UpdateReward changes the settings of a channel-point reward and pushes them to Twitch
+func UpdateReward(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+
+	rewardType, ok := twitch.ParseRewardType(vars["rewardType"])
+	if !ok {
+		writeAPIError(w, "Unknown reward type", http.StatusBadRequest)
+		return
+	}
+
+	var req RewardSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	rewardListener := twitch.GetRewardListener(userID)
+	if rewardListener == nil {
+		writeAPIError(w, "User not found or not active", http.StatusNotFound)
+		return
+	}
+
+	var current twitch.RewardInfo
+	for _, reward := range rewardListener.Rewards() {
+		if reward.Type == rewardType {
+			current = reward
+		}
+	}
+
+	settings := current.Settings
+	if req.Title != nil {
+		settings.Title = *req.Title
+	}
+	if req.Cost != nil {
+		settings.Cost = *req.Cost
+	}
+	if req.Prompt != nil {
+		settings.Prompt = *req.Prompt
+	}
+	if req.BackgroundColor != nil {
+		settings.BackgroundColor = *req.BackgroundColor
+	}
+	if req.GlobalCooldown != nil {
+		settings.GlobalCooldown = *req.GlobalCooldown
+	}
+	if req.MaxPerStream != nil {
+		settings.MaxPerStream = *req.MaxPerStream
+	}
+	if req.MaxPerUserPerStream != nil {
+		settings.MaxPerUserPerStream = *req.MaxPerUserPerStream
+	}
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+
+	if err := settings.Validate(); err != nil {
+		writeAPIError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := rewardListener.UpdateRewardSettings(rewardType, settings); err != nil {
+		log.Printf("Error updating reward %s for user %s: %v", vars["rewardType"], userID, err)
+		writeAPIError(w, "Failed to update reward", http.StatusInternalServerError)
+		return
+	}
+
+	// Enabling or disabling creates or deletes the reward on Twitch
+	for _, reward := range rewardListener.Rewards() {
+		if reward.Type == rewardType {
+			current = reward
+		}
+	}
+	writeAPISuccess(w, rewardToResponse(current))
+}
+
+// rewardToResponse converts a reward to its API representation
+func rewardToResponse(reward twitch.RewardInfo) RewardResponse {
+	return RewardResponse{
+		Type:                twitch.RewardTypeName(reward.Type),
+		TwitchID:            reward.TwitchID,
+		Title:               reward.Settings.Title,
+		Cost:                reward.Settings.Cost,
+		Prompt:              reward.Settings.Prompt,
+		BackgroundColor:     reward.Settings.BackgroundColor,
+		GlobalCooldown:      reward.Settings.GlobalCooldown,
+		MaxPerStream:        reward.Settings.MaxPerStream,
+		MaxPerUserPerStream: reward.Settings.MaxPerUserPerStream,
+		Enabled:             reward.Settings.Enabled,
+	}
+}
@@ -43,8 +43,14 @@ func RegisterRoutes(r *mux.Router) {
 	userAPI.Use(UserValidationMiddleware)
 	userAPI.HandleFunc("/profile", GetUserProfile).Methods("GET")
 	userAPI.HandleFunc("/queue", GetQueue).Methods("GET")
+	userAPI.HandleFunc("/queue/{requestID}", RemoveQueuedRequest).Methods("DELETE")
+	userAPI.HandleFunc("/queue/{requestID}/position", MoveQueuedRequest).Methods("PUT")
 	userAPI.HandleFunc("/settings", UpdateUserSettings).Methods("POST", "PUT")
 	userAPI.HandleFunc("/fix-rewards", FixRewards).Methods("POST")
+	userAPI.HandleFunc("/rewards", GetRewards).Methods("GET")
+	userAPI.HandleFunc("/rewards/{rewardType}", UpdateReward).Methods("PUT")
+	userAPI.HandleFunc("/devices", GetDevices).Methods("GET")
+	userAPI.HandleFunc("/devices/preferred", SetPreferredDevice).Methods("PUT")
 
 	// New settings and blocks endpoints
 	userAPI.HandleFunc("/config", GetSettings).Methods("GET")
@@ -53,6 +59,9 @@ func RegisterRoutes(r *mux.Router) {
 	userAPI.HandleFunc("/blocks", AddBlock).Methods("POST")
 	userAPI.HandleFunc("/blocks/{blockID}", RemoveBlock).Methods("DELETE")
 	userAPI.HandleFunc("/spotify/search", SpotifySearch).Methods("GET")
+	userAPI.HandleFunc("/playlists", GetAllowedPlaylists).Methods("GET")
+	userAPI.HandleFunc("/playlists", AddAllowedPlaylist).Methods("POST")
+	userAPI.HandleFunc("/playlists/{playlistID}", RemoveAllowedPlaylist).Methods("DELETE")
 
 	// Moderator endpoints
 	userAPI.HandleFunc("/moderators", GetModerators).Methods("GET")
@@ -66,6 +75,9 @@ func RegisterRoutes(r *mux.Router) {
 	userAPI.HandleFunc("/commands/initialize", InitializeCommands).Methods("POST")
 	userAPI.HandleFunc("/request-mode", ToggleRequestMode).Methods("POST", "PUT")
 
+	// Request log endpoints
+	userAPI.HandleFunc("/requests", GetRequestLog).Methods("GET")
+
 	// Enable CORS for all API routes
 	api.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,79 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// DefaultAppleMusicBaseURL is the host of the iTunes lookup API
+const DefaultAppleMusicBaseURL = "https://itunes.apple.com"
+
+var (
+	// appleMusicLinkRegex matches music.apple.com album, song and track links
+	appleMusicLinkRegex = regexp.MustCompile(`(?i)(?:https?://)?(?:music|itunes)\.apple\.com/[a-z]{2}/(?:album|song)/\S+`)
+	// appleMusicTrackIDRegex extracts the track ID from the ?i= parameter or a /song/ path
+	appleMusicTrackIDRegex = regexp.MustCompile(`(?i)(?:[?&]i=|/song/(?:[^/\s?]+/)?)(\d+)`)
+)
+
+// AppleMusicResolver resolves Apple Music links through the iTunes lookup API
+type AppleMusicResolver struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewAppleMusicResolver creates an Apple Music resolver; empty arguments select the public API
+func NewAppleMusicResolver(baseURL string, httpClient *http.Client) *AppleMusicResolver {
+	if baseURL == "" {
+		baseURL = DefaultAppleMusicBaseURL
+	}
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+	return &AppleMusicResolver{BaseURL: baseURL, HTTPClient: httpClient}
+}
+
+// Name returns the platform name
+func (r *AppleMusicResolver) Name() string {
+	return "apple_music"
+}
+
+// FindLink returns the first Apple Music link in the text
+func (r *AppleMusicResolver) FindLink(text string) string {
+	return appleMusicLinkRegex.FindString(text)
+}
+
+// iTunesLookup is the part of the lookup response we use
+type iTunesLookup struct {
+	Results []struct {
+		WrapperType string `json:"wrapperType"`
+		Kind        string `json:"kind"`
+		TrackName   string `json:"trackName"`
+		ArtistName  string `json:"artistName"`
+	} `json:"results"`
+}
+
+// Resolve looks up the song behind an Apple Music link. Album links without a selected song are rejected.
+func (r *AppleMusicResolver) Resolve(ctx context.Context, link string) (*TrackInfo, error) {
+	matches := appleMusicTrackIDRegex.FindStringSubmatch(link)
+	if len(matches) < 2 {
+		return nil, fmt.Errorf("Apple Music link does not point to a single song: %s", link)
+	}
+
+	endpoint := fmt.Sprintf("%s/lookup?entity=song&id=%s", strings.TrimRight(r.BaseURL, "/"), matches[1])
+
+	var data iTunesLookup
+	if err := getJSON(ctx, r.HTTPClient, endpoint, &data); err != nil {
+		return nil, err
+	}
+
+	for _, result := range data.Results {
+		if result.WrapperType == "track" && result.TrackName != "" {
+			return &TrackInfo{Source: r.Name(), Artist: result.ArtistName, Title: result.TrackName}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Apple Music song %s not found", matches[1])
+}
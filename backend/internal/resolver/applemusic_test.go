@@ -0,0 +1,76 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// appleMusicLookup is an iTunes lookup response for a song
+const appleMusicLookup = `{
+  "resultCount": 1,
+  "results": [
+    {"wrapperType": "track", "kind": "song", "trackId": 1440806768, "artistName": "Queen", "trackName": "Bohemian Rhapsody", "collectionName": "A Night at the Opera"}
+  ]
+}`
+
+func TestAppleMusicResolve(t *testing.T) {
+	tests := []struct {
+		name   string
+		link   string
+		wantID string // Track ID that must be looked up
+	}{
+		{"album link with song", "https://music.apple.com/us/album/bohemian-rhapsody/1440806041?i=1440806768", "1440806768"},
+		{"album link with song and params", "https://music.apple.com/gb/album/a-night-at-the-opera/1440806041?ls=1&i=1440806768", "1440806768"},
+		{"song link", "https://music.apple.com/us/song/bohemian-rhapsody/1440806768", "1440806768"},
+		{"song link without name", "https://music.apple.com/us/song/1440806768", "1440806768"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := standIn(t, func(r *http.Request) (int, string) {
+				if r.URL.Path != "/lookup" || r.URL.Query().Get("id") != tt.wantID || r.URL.Query().Get("entity") != "song" {
+					t.Errorf("unexpected request %s", r.URL)
+				}
+				return http.StatusOK, appleMusicLookup
+			})
+
+			info, err := NewAppleMusicResolver(server.URL, server.Client()).Resolve(context.Background(), tt.link)
+			if err != nil {
+				t.Fatalf("Resolve(%q): %v", tt.link, err)
+			}
+			if info.Source != "apple_music" || info.Artist != "Queen" || info.Title != "Bohemian Rhapsody" {
+				t.Errorf("Resolve(%q) = %+v, want Queen - Bohemian Rhapsody", tt.link, info)
+			}
+		})
+	}
+}
+
+func TestAppleMusicResolveErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		body string
+	}{
+		{"album link is rejected", "https://music.apple.com/us/album/a-night-at-the-opera/1440806041", ""},
+		{"song not found", "https://music.apple.com/us/song/bohemian-rhapsody/1", `{"resultCount": 0, "results": []}`},
+		{"collection instead of song", "https://music.apple.com/us/song/bohemian-rhapsody/1440806041",
+			`{"resultCount": 1, "results": [{"wrapperType": "collection", "collectionName": "A Night at the Opera"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := standIn(t, func(r *http.Request) (int, string) {
+				if tt.body == "" {
+					t.Errorf("unexpected request %s", r.URL)
+				}
+				return http.StatusOK, tt.body
+			})
+
+			info, err := NewAppleMusicResolver(server.URL, server.Client()).Resolve(context.Background(), tt.link)
+			if err == nil {
+				t.Errorf("Resolve(%q) = %+v, want an error", tt.link, info)
+			}
+		})
+	}
+}
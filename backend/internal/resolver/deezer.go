@@ -0,0 +1,76 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// DefaultDeezerBaseURL is the host of the Deezer public API
+const DefaultDeezerBaseURL = "https://api.deezer.com"
+
+// deezerLinkRegex matches deezer.com track links with an optional language prefix
+var deezerLinkRegex = regexp.MustCompile(`(?i)(?:https?://)?(?:www\.)?deezer\.com/(?:[a-z]{2}/)?track/(\d+)`)
+
+// DeezerResolver resolves Deezer track links through the Deezer API, which exposes ISRCs
+type DeezerResolver struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewDeezerResolver creates a Deezer resolver; empty arguments select the public API
+func NewDeezerResolver(baseURL string, httpClient *http.Client) *DeezerResolver {
+	if baseURL == "" {
+		baseURL = DefaultDeezerBaseURL
+	}
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+	return &DeezerResolver{BaseURL: baseURL, HTTPClient: httpClient}
+}
+
+// Name returns the platform name
+func (r *DeezerResolver) Name() string {
+	return "deezer"
+}
+
+// FindLink returns the first Deezer track link in the text
+func (r *DeezerResolver) FindLink(text string) string {
+	return deezerLinkRegex.FindString(text)
+}
+
+// deezerTrack is the part of the track response we use
+type deezerTrack struct {
+	Title  string `json:"title"`
+	ISRC   string `json:"isrc"`
+	Artist struct {
+		Name string `json:"name"`
+	} `json:"artist"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Resolve looks up the track behind a Deezer link
+func (r *DeezerResolver) Resolve(ctx context.Context, link string) (*TrackInfo, error) {
+	matches := deezerLinkRegex.FindStringSubmatch(link)
+	if len(matches) < 2 {
+		return nil, fmt.Errorf("not a Deezer track link: %s", link)
+	}
+
+	endpoint := fmt.Sprintf("%s/track/%s", strings.TrimRight(r.BaseURL, "/"), matches[1])
+
+	var data deezerTrack
+	if err := getJSON(ctx, r.HTTPClient, endpoint, &data); err != nil {
+		return nil, err
+	}
+
+	// Deezer reports errors with a 200 status
+	if data.Error != nil {
+		return nil, fmt.Errorf("Deezer track %s: %s", matches[1], data.Error.Message)
+	}
+
+	return &TrackInfo{Source: r.Name(), Artist: data.Artist.Name, Title: data.Title, ISRC: data.ISRC}, nil
+}
@@ -0,0 +1,53 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestDeezerResolve(t *testing.T) {
+	server := standIn(t, func(r *http.Request) (int, string) {
+		if r.URL.Path != "/track/3135556" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		return http.StatusOK, `{"id": 3135556, "title": "Harder, Better, Faster, Stronger", "isrc": "GBDUW0000059", "artist": {"id": 27, "name": "Daft Punk"}}`
+	})
+
+	for _, link := range []string{"https://www.deezer.com/en/track/3135556", "deezer.com/track/3135556"} {
+		info, err := NewDeezerResolver(server.URL, server.Client()).Resolve(context.Background(), link)
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", link, err)
+		}
+		want := TrackInfo{Source: "deezer", Artist: "Daft Punk", Title: "Harder, Better, Faster, Stronger", ISRC: "GBDUW0000059"}
+		if *info != want {
+			t.Errorf("Resolve(%q) = %+v, want %+v", link, *info, want)
+		}
+	}
+}
+
+func TestDeezerResolveErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		// Deezer answers missing tracks with a 200 status and an error object
+		{"error with status 200", http.StatusOK, `{"error": {"type": "DataException", "message": "no data", "code": 800}}`},
+		{"server error", http.StatusInternalServerError, `{}`},
+		{"invalid body", http.StatusOK, `<html>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := standIn(t, func(r *http.Request) (int, string) {
+				return tt.status, tt.body
+			})
+
+			info, err := NewDeezerResolver(server.URL, server.Client()).Resolve(context.Background(), "https://www.deezer.com/track/1")
+			if err == nil {
+				t.Errorf("Resolve = %+v, want an error", info)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TrackInfo describes a track found through another platform's link
+type TrackInfo struct {
+	Source string // Name of the resolver that produced it
+	Artist string
+	Title  string
+	ISRC   string // Empty when the platform doesn't expose it
+}
+
+// Query returns an "artist - title" search query for the track
+func (t *TrackInfo) Query() string {
+	if t.Artist == "" {
+		return t.Title
+	}
+	return t.Artist + " - " + t.Title
+}
+
+// Resolver extracts track information from links of a music platform
+type Resolver interface {
+	// Name returns the platform name
+	Name() string
+	// FindLink returns the first link of this platform in the text, or an empty string
+	FindLink(text string) string
+	// Resolve looks up the track behind a link
+	Resolve(ctx context.Context, link string) (*TrackInfo, error)
+}
+
+// defaultHTTPClient is used by resolvers created without a client
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// DefaultResolvers returns resolvers for all supported platforms using their public APIs
+func DefaultResolvers() []Resolver {
+	return []Resolver{
+		NewYouTubeResolver("", nil),
+		NewAppleMusicResolver("", nil),
+		NewDeezerResolver("", nil),
+	}
+}
+
+// FindLink returns the resolver for the first supported link in the text and the link itself.
+// Returns a nil resolver if the text contains no supported link.
+func FindLink(resolvers []Resolver, text string) (Resolver, string) {
+	var found Resolver
+	foundLink, foundAt := "", len(text)
+	for _, r := range resolvers {
+		link := r.FindLink(text)
+		if link == "" {
+			continue
+		}
+		if at := strings.Index(text, link); at < foundAt {
+			found, foundLink, foundAt = r, link, at
+		}
+	}
+	return found, foundLink
+}
+
+// getJSON fetches a URL and decodes its JSON body into target
+func getJSON(ctx context.Context, client *http.Client, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed with status %d", url, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", url, err)
+	}
+	return nil
+}
+
+// videoTitleNoise matches decorations that video titles add to song names
+var videoTitleNoise = regexp.MustCompile(`(?i)\s*[(\[](official\s*(music\s*)?(video|audio|lyric\s*video|visualizer)|lyrics?(\s*video)?|audio|hd|hq|4k|mv|m/v|clip officiel|videoclip)[)\]]`)
+
+// cleanVideoTitle strips "(Official Video)" style decorations from a title
+func cleanVideoTitle(title string) string {
+	return strings.TrimSpace(videoTitleNoise.ReplaceAllString(title, ""))
+}
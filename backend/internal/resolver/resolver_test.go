@@ -0,0 +1,63 @@
+package resolver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// standIn starts a local HTTP server standing in for a platform API. It answers every request with the
+// body and status returned by respond, and fails the test on requests respond doesn't expect.
+func standIn(t *testing.T, respond func(r *http.Request) (int, string)) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, body := respond(r)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFindLink(t *testing.T) {
+	resolvers := DefaultResolvers()
+
+	tests := []struct {
+		name     string
+		text     string
+		wantName string // Empty when no resolver should match
+		wantLink string
+	}{
+		{"youtube watch", "!sr https://www.youtube.com/watch?v=dQw4w9WgXcQ please", "youtube", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"youtube watch with params", "https://youtube.com/watch?list=abc&v=dQw4w9WgXcQ", "youtube", "https://youtube.com/watch?list=abc&v=dQw4w9WgXcQ"},
+		{"youtube music", "music.youtube.com/watch?v=dQw4w9WgXcQ", "youtube", "music.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"youtube short link", "https://youtu.be/dQw4w9WgXcQ?si=x", "youtube", "https://youtu.be/dQw4w9WgXcQ"},
+		{"youtube shorts", "https://www.youtube.com/shorts/dQw4w9WgXcQ", "youtube", "https://www.youtube.com/shorts/dQw4w9WgXcQ"},
+		{"apple music", "https://music.apple.com/us/album/bohemian-rhapsody/1440806041?i=1440806768", "apple_music", "https://music.apple.com/us/album/bohemian-rhapsody/1440806041?i=1440806768"},
+		{"deezer", "https://www.deezer.com/en/track/3135556", "deezer", "https://www.deezer.com/en/track/3135556"},
+		{"deezer without language", "deezer.com/track/3135556", "deezer", "deezer.com/track/3135556"},
+		{"first link in the text wins", "https://www.deezer.com/track/3135556 https://youtu.be/dQw4w9WgXcQ", "deezer", "https://www.deezer.com/track/3135556"},
+		{"spotify is not resolved", "https://open.spotify.com/track/4u7EnebtmKWzUH433cf5Qv", "", ""},
+		{"plain text", "Queen - Bohemian Rhapsody", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, link := FindLink(resolvers, tt.text)
+			if tt.wantName == "" {
+				if r != nil {
+					t.Fatalf("FindLink(%q) = %s %q, want no match", tt.text, r.Name(), link)
+				}
+				return
+			}
+			if r == nil {
+				t.Fatalf("FindLink(%q) found nothing, want %s", tt.text, tt.wantName)
+			}
+			if r.Name() != tt.wantName || link != tt.wantLink {
+				t.Errorf("FindLink(%q) = %s %q, want %s %q", tt.text, r.Name(), link, tt.wantName, tt.wantLink)
+			}
+		})
+	}
+}
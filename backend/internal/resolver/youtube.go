@@ -0,0 +1,87 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultYouTubeBaseURL is the host of the YouTube oEmbed endpoint
+const DefaultYouTubeBaseURL = "https://www.youtube.com"
+
+// youTubeLinkRegex matches youtube.com, music.youtube.com, shorts and youtu.be links
+var youTubeLinkRegex = regexp.MustCompile(`(?i)(?:https?://)?(?:(?:www\.|m\.|music\.)?youtube\.com/(?:watch\?(?:\S*&)?v=|shorts/)|youtu\.be/)([0-9A-Za-z_-]{11})`)
+
+// videoTitleSeparators split "Artist - Title" video titles
+var videoTitleSeparators = []string{" - ", " – ", " — "}
+
+// YouTubeResolver resolves YouTube and YouTube Music links through oEmbed
+type YouTubeResolver struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewYouTubeResolver creates a YouTube resolver; empty arguments select the public API
+func NewYouTubeResolver(baseURL string, httpClient *http.Client) *YouTubeResolver {
+	if baseURL == "" {
+		baseURL = DefaultYouTubeBaseURL
+	}
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+	return &YouTubeResolver{BaseURL: baseURL, HTTPClient: httpClient}
+}
+
+// Name returns the platform name
+func (r *YouTubeResolver) Name() string {
+	return "youtube"
+}
+
+// FindLink returns the first YouTube link in the text
+func (r *YouTubeResolver) FindLink(text string) string {
+	return youTubeLinkRegex.FindString(text)
+}
+
+// youTubeOEmbed is the part of the oEmbed response we use
+type youTubeOEmbed struct {
+	Title      string `json:"title"`
+	AuthorName string `json:"author_name"`
+}
+
+// Resolve looks up the video title and channel and splits them into artist and title
+func (r *YouTubeResolver) Resolve(ctx context.Context, link string) (*TrackInfo, error) {
+	matches := youTubeLinkRegex.FindStringSubmatch(link)
+	if len(matches) < 2 {
+		return nil, fmt.Errorf("not a YouTube link: %s", link)
+	}
+
+	videoURL := "https://www.youtube.com/watch?v=" + matches[1]
+	endpoint := fmt.Sprintf("%s/oembed?format=json&url=%s", strings.TrimRight(r.BaseURL, "/"), url.QueryEscape(videoURL))
+
+	var data youTubeOEmbed
+	if err := getJSON(ctx, r.HTTPClient, endpoint, &data); err != nil {
+		return nil, err
+	}
+
+	title := cleanVideoTitle(data.Title)
+	if title == "" {
+		return nil, fmt.Errorf("YouTube video %s has no title", matches[1])
+	}
+
+	// Music videos are usually titled "Artist - Title"; auto-generated
+	// YouTube Music uploads use the title alone with an "Artist - Topic" channel
+	for _, separator := range videoTitleSeparators {
+		if artist, song, found := strings.Cut(title, separator); found {
+			return &TrackInfo{Source: r.Name(), Artist: strings.TrimSpace(artist), Title: strings.TrimSpace(song)}, nil
+		}
+	}
+
+	return &TrackInfo{
+		Source: r.Name(),
+		Artist: strings.TrimSpace(strings.TrimSuffix(data.AuthorName, " - Topic")),
+		Title:  title,
+	}, nil
+}
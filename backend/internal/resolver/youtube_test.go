@@ -0,0 +1,69 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestYouTubeResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		link       string
+		title      string // oEmbed video title
+		author     string // oEmbed channel name
+		wantArtist string
+		wantTitle  string
+	}{
+		{"artist - title", "https://www.youtube.com/watch?v=fJ9rUzIMcZQ", "Queen - Bohemian Rhapsody", "Queen Official", "Queen", "Bohemian Rhapsody"},
+		{"artist – title with en dash", "https://www.youtube.com/watch?v=fJ9rUzIMcZQ", "Queen – Bohemian Rhapsody", "Queen Official", "Queen", "Bohemian Rhapsody"},
+		{"topic channel", "https://music.youtube.com/watch?v=4NRXx6U8ABQ", "Blinding Lights", "The Weeknd - Topic", "The Weeknd", "Blinding Lights"},
+		{"official video noise", "https://youtu.be/4NRXx6U8ABQ", "The Weeknd - Blinding Lights (Official Video)", "TheWeekndVEVO", "The Weeknd", "Blinding Lights"},
+		{"lyrics and hd noise", "https://youtu.be/4NRXx6U8ABQ", "The Weeknd - Save Your Tears [Lyrics] (HD)", "Lyrics Channel", "The Weeknd", "Save Your Tears"},
+		{"noise without artist", "https://youtu.be/4NRXx6U8ABQ", "Blinding Lights (Official Audio)", "The Weeknd - Topic", "The Weeknd", "Blinding Lights"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := standIn(t, func(r *http.Request) (int, string) {
+				videoID := youTubeLinkRegex.FindStringSubmatch(tt.link)[1]
+				if r.URL.Path != "/oembed" || r.URL.Query().Get("url") != "https://www.youtube.com/watch?v="+videoID {
+					t.Errorf("unexpected request %s", r.URL)
+				}
+				return http.StatusOK, `{"title": "` + tt.title + `", "author_name": "` + tt.author + `", "type": "video"}`
+			})
+
+			info, err := NewYouTubeResolver(server.URL, server.Client()).Resolve(context.Background(), tt.link)
+			if err != nil {
+				t.Fatalf("Resolve(%q): %v", tt.link, err)
+			}
+			if info.Source != "youtube" || info.Artist != tt.wantArtist || info.Title != tt.wantTitle {
+				t.Errorf("Resolve(%q) = %+v, want artist %q and title %q", tt.link, info, tt.wantArtist, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestYouTubeResolveErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"missing video", http.StatusNotFound, `Not Found`},
+		{"only noise in title", http.StatusOK, `{"title": "(Official Video)", "author_name": "Someone"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := standIn(t, func(r *http.Request) (int, string) {
+				return tt.status, tt.body
+			})
+
+			info, err := NewYouTubeResolver(server.URL, server.Client()).Resolve(context.Background(), "https://youtu.be/dQw4w9WgXcQ")
+			if err == nil {
+				t.Errorf("Resolve = %+v, want an error", info)
+			}
+		})
+	}
+}
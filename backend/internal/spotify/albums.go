@@ -0,0 +1,98 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// AlbumCache keeps full album objects, whose labels and copyrights rarely change
+type AlbumCache struct {
+	holdTime time.Duration
+	store    map[spotify.ID]cachedAlbum
+	mutex    sync.RWMutex
+}
+
+type cachedAlbum struct {
+	album    *spotify.FullAlbum
+	cachedAt time.Time
+}
+
+// NewAlbumCache creates a new album cache with 24 hour hold time
+func NewAlbumCache() *AlbumCache {
+	return &AlbumCache{
+		holdTime: 24 * time.Hour,
+		store:    make(map[spotify.ID]cachedAlbum),
+	}
+}
+
+// Get returns a cached album, or nil if it isn't cached or has expired
+func (ac *AlbumCache) Get(id spotify.ID) *spotify.FullAlbum {
+	ac.mutex.RLock()
+	defer ac.mutex.RUnlock()
+
+	entry, exists := ac.store[id]
+	if !exists || time.Since(entry.cachedAt) >= ac.holdTime {
+		return nil
+	}
+	return entry.album
+}
+
+// Add caches an album
+func (ac *AlbumCache) Add(album *spotify.FullAlbum) {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	ac.store[album.ID] = cachedAlbum{album: album, cachedAt: time.Now()}
+}
+
+// Cleanup removes all expired entries from the cache
+func (ac *AlbumCache) Cleanup() {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	now := time.Now()
+	for id, entry := range ac.store {
+		if now.Sub(entry.cachedAt) >= ac.holdTime {
+			delete(ac.store, id)
+		}
+	}
+}
+
+// Global album cache instance
+var GlobalAlbumCache = NewAlbumCache()
+
+// GetAlbum returns a full album object, using the album cache where possible
+func (s *SpotifyClient) GetAlbum(id spotify.ID) (*spotify.FullAlbum, error) {
+	if album := GlobalAlbumCache.Get(id); album != nil {
+		return album, nil
+	}
+
+	var album *spotify.FullAlbum
+	err := s.executeWithRetry(func() error {
+		var err error
+		album, err = s.client.GetAlbum(context.Background(), id)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get album %s: %w", id, err)
+	}
+
+	GlobalAlbumCache.Add(album)
+	return album, nil
+}
+
+// AlbumLabels returns the label and copyright statements of an album.
+// The Web API client exposes no label field, so the copyright lines, which name the label, stand in for it.
+func AlbumLabels(album *spotify.FullAlbum) []string {
+	var labels []string
+	for _, copyright := range album.Copyrights {
+		if copyright.Text != "" {
+			labels = append(labels, copyright.Text)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,107 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// maxArtistsPerRequest is the Spotify limit for the several artists endpoint
+const maxArtistsPerRequest = 50
+
+// ArtistCache keeps full artist objects, whose genres rarely change
+type ArtistCache struct {
+	holdTime time.Duration
+	store    map[spotify.ID]cachedArtist
+	mutex    sync.RWMutex
+}
+
+type cachedArtist struct {
+	artist   *spotify.FullArtist
+	cachedAt time.Time
+}
+
+// NewArtistCache creates a new artist cache with 24 hour hold time
+func NewArtistCache() *ArtistCache {
+	return &ArtistCache{
+		holdTime: 24 * time.Hour,
+		store:    make(map[spotify.ID]cachedArtist),
+	}
+}
+
+// Get returns a cached artist, or nil if it isn't cached or has expired
+func (ac *ArtistCache) Get(id spotify.ID) *spotify.FullArtist {
+	ac.mutex.RLock()
+	defer ac.mutex.RUnlock()
+
+	entry, exists := ac.store[id]
+	if !exists || time.Since(entry.cachedAt) >= ac.holdTime {
+		return nil
+	}
+	return entry.artist
+}
+
+// Add caches an artist
+func (ac *ArtistCache) Add(artist *spotify.FullArtist) {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	ac.store[artist.ID] = cachedArtist{artist: artist, cachedAt: time.Now()}
+}
+
+// Cleanup removes all expired entries from the cache
+func (ac *ArtistCache) Cleanup() {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	now := time.Now()
+	for id, entry := range ac.store {
+		if now.Sub(entry.cachedAt) >= ac.holdTime {
+			delete(ac.store, id)
+		}
+	}
+}
+
+// Global artist cache instance
+var GlobalArtistCache = NewArtistCache()
+
+// GetArtists returns full artist objects, using the artist cache where possible
+func (s *SpotifyClient) GetArtists(ids []spotify.ID) ([]*spotify.FullArtist, error) {
+	var artists []*spotify.FullArtist
+	var missing []spotify.ID
+	for _, id := range ids {
+		if artist := GlobalArtistCache.Get(id); artist != nil {
+			artists = append(artists, artist)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	ctx := context.Background()
+	for start := 0; start < len(missing); start += maxArtistsPerRequest {
+		end := min(start+maxArtistsPerRequest, len(missing))
+
+		var fetched []*spotify.FullArtist
+		err := s.executeWithRetry(func() error {
+			var err error
+			fetched, err = s.client.GetArtists(ctx, missing[start:end]...)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get artists: %w", err)
+		}
+
+		for _, artist := range fetched {
+			if artist == nil {
+				continue
+			}
+			GlobalArtistCache.Add(artist)
+			artists = append(artists, artist)
+		}
+	}
+
+	return artists, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -36,6 +37,7 @@ type SpotifyClient struct {
 	currentToken   *oauth2.Token
 	clientID       string
 	clientSecret   string
+	deviceID       spotify.ID // Device player commands target; empty for whichever device is active
 	mutex          sync.RWMutex
 }
 
@@ -168,6 +170,81 @@ func (s *SpotifyClient) refreshToken() error {
 	return nil
 }
 
+// ErrNoActiveDevice is returned by player commands when the user has no active Spotify device
+var ErrNoActiveDevice = errors.New("no active Spotify device")
+
+// asNoActiveDevice maps Spotify's "no active device" player error to ErrNoActiveDevice
+func asNoActiveDevice(err error) error {
+	var apiErr spotify.Error
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		// A targeted device that is offline is reported as not found
+		message := strings.ToLower(apiErr.Message)
+		if strings.Contains(message, "no active device") || strings.Contains(message, "device not found") {
+			return ErrNoActiveDevice
+		}
+	}
+	return err
+}
+
+// SetDeviceID sets the device player commands target. An empty ID targets whichever device is active.
+func (s *SpotifyClient) SetDeviceID(deviceID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.deviceID = spotify.ID(deviceID)
+}
+
+// DeviceID returns the device player commands target, or an empty string for the active device
+func (s *SpotifyClient) DeviceID() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return string(s.deviceID)
+}
+
+// playOptions returns the options that point a player command at the target device
+func (s *SpotifyClient) playOptions() *spotify.PlayOptions {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.deviceID == "" {
+		return nil
+	}
+	deviceID := s.deviceID
+	return &spotify.PlayOptions{DeviceID: &deviceID}
+}
+
+// GetDevices returns the user's available Spotify Connect devices
+func (s *SpotifyClient) GetDevices() ([]spotify.PlayerDevice, error) {
+	ctx := context.Background()
+	var devices []spotify.PlayerDevice
+
+	err := s.executeWithRetry(func() error {
+		var err error
+		devices, err = s.client.PlayerDevices(ctx)
+		return err
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devices: %w", err)
+	}
+	return devices, nil
+}
+
+// TransferPlayback moves playback to a device, starting it if play is set
+func (s *SpotifyClient) TransferPlayback(deviceID string, play bool) error {
+	ctx := context.Background()
+
+	err := s.executeWithRetry(func() error {
+		return s.client.TransferPlayback(ctx, spotify.ID(deviceID), play)
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to transfer playback to device %s: %w", deviceID, asNoActiveDevice(err))
+	}
+	return nil
+}
+
 // executeWithRetry executes a function and retries with token refresh on 401 errors
 func (s *SpotifyClient) executeWithRetry(fn func() error) error {
 	err := fn()
@@ -234,14 +311,87 @@ func SongItemToReadableSimple(track *spotify.SimpleTrack) string {
 	return leftPart + " - " + rightPart
 }
 
+// SearchTrack searches Spotify for the track that best matches a request query.
+// Returns nil if nothing was found.
+func (s *SpotifyClient) SearchTrack(query string) (*spotify.FullTrack, error) {
+	parsed := ParseSearchQuery(query)
+
+	candidates, err := s.searchCandidates(parsed.SpotifyQuery())
+	if err != nil {
+		return nil, err
+	}
+
+	// Field filters are strict about spelling and viewers also write "title - artist", so unless a
+	// candidate matches well, try the swapped fields and then free text, scoring all candidates together
+	if parsed.Artist != "" {
+		for _, fallback := range []string{parsed.Swapped().SpotifyQuery(), parsed.Raw} {
+			if isStrongMatch(parsed, candidates) {
+				break
+			}
+			more, err := s.searchCandidates(fallback)
+			if err != nil {
+				return nil, err
+			}
+			candidates = mergeTracks(candidates, more)
+		}
+	}
+
+	return BestTrack(parsed, candidates), nil
+}
+
+// searchCandidates returns the tracks of a search, which may be none
+func (s *SpotifyClient) searchCandidates(query string) ([]spotify.FullTrack, error) {
+	results, err := s.SearchTracks(query, SearchCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+	if results.Tracks == nil {
+		return nil, nil
+	}
+	return results.Tracks.Tracks, nil
+}
+
+// FindCleanVersion searches for a non-explicit version of an explicit track.
+// Returns nil if Spotify has none.
+func (s *SpotifyClient) FindCleanVersion(track *spotify.FullTrack) (*spotify.FullTrack, error) {
+	if len(track.Artists) == 0 {
+		return nil, nil
+	}
+
+	q := SearchQuery{Artist: track.Artists[0].Name, Title: stripVersionInfo(track.Name)}
+	results, err := s.SearchTracks(q.SpotifyQuery(), SearchCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	if results.Tracks == nil {
+		return nil, nil
+	}
+	return CleanVersion(track, results.Tracks.Tracks), nil
+}
+
+// SearchTrackByISRC finds the track with the given ISRC.
+// Returns nil if Spotify has no such track.
+func (s *SpotifyClient) SearchTrackByISRC(isrc string) (*spotify.FullTrack, error) {
+	results, err := s.SearchTracks("isrc:"+isrc, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if results.Tracks == nil || len(results.Tracks.Tracks) == 0 {
+		return nil, nil
+	}
+	return &results.Tracks.Tracks[0], nil
+}
+
 // SearchTracks searches for tracks on Spotify
-func (s *SpotifyClient) SearchTracks(query string) (*spotify.SearchResult, error) {
+func (s *SpotifyClient) SearchTracks(query string, limit int) (*spotify.SearchResult, error) {
 	ctx := context.Background()
 	var results *spotify.SearchResult
 
 	err := s.executeWithRetry(func() error {
 		var err error
-		results, err = s.client.Search(ctx, query, spotify.SearchTypeTrack, spotify.Limit(1))
+		results, err = s.client.Search(ctx, query, spotify.SearchTypeTrack, spotify.Limit(limit))
 		return err
 	})
 
@@ -266,11 +416,11 @@ func (s *SpotifyClient) EnqueueTrack(trackURI spotify.URI) error {
 	log.Printf("Enqueueing track - URI: %s, ID: %s", uriStr, trackID)
 
 	err := s.executeWithRetry(func() error {
-		return s.client.QueueSong(ctx, trackID)
+		return s.client.QueueSongOpt(ctx, trackID, s.playOptions())
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to enqueue track: %w", err)
+		return fmt.Errorf("failed to enqueue track: %w", asNoActiveDevice(err))
 	}
 	return nil
 }
@@ -280,11 +430,11 @@ func (s *SpotifyClient) NextTrack() error {
 	ctx := context.Background()
 
 	err := s.executeWithRetry(func() error {
-		return s.client.Next(ctx)
+		return s.client.NextOpt(ctx, s.playOptions())
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to skip track: %w", err)
+		return fmt.Errorf("failed to skip track: %w", asNoActiveDevice(err))
 	}
 	return nil
 }
@@ -352,18 +502,51 @@ func (s *SpotifyClient) SetVolume(volume int) error {
 	ctx := context.Background()
 
 	err := s.executeWithRetry(func() error {
-		return s.client.Volume(ctx, volume)
+		return s.client.VolumeOpt(ctx, volume, s.playOptions())
 	})
 
 	if err != nil {
 		log.Printf("Spotify API error setting volume to %d%%: %v", volume, err)
-		return fmt.Errorf("failed to set volume: %w", err)
+		return fmt.Errorf("failed to set volume: %w", asNoActiveDevice(err))
 	}
 
 	log.Printf("Successfully set Spotify volume to %d%%", volume)
 	return nil
 }
 
+// GetVolume returns the volume of the target device
+func (s *SpotifyClient) GetVolume() (int, error) {
+	if deviceID := s.DeviceID(); deviceID != "" {
+		devices, err := s.GetDevices()
+		if err != nil {
+			return 0, err
+		}
+		for _, device := range devices {
+			if string(device.ID) == deviceID {
+				return int(device.Volume), nil
+			}
+		}
+		return 0, ErrNoActiveDevice
+	}
+
+	ctx := context.Background()
+	var state *spotify.PlayerState
+
+	err := s.executeWithRetry(func() error {
+		var err error
+		state, err = s.client.PlayerState(ctx)
+		return err
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to get player state: %w", err)
+	}
+	if state == nil || state.Device.ID == "" {
+		return 0, ErrNoActiveDevice
+	}
+	return int(state.Device.Volume), nil
+}
+
 // GetQueue gets the user's queue from Spotify
 func (s *SpotifyClient) GetQueue() (*spotify.Queue, error) {
 	ctx := context.Background()
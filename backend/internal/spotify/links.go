@@ -0,0 +1,110 @@
+package spotify
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LinkType is the kind of Spotify entity a link points to
+type LinkType string
+
+const (
+	LinkTypeTrack    LinkType = "track"
+	LinkTypeAlbum    LinkType = "album"
+	LinkTypePlaylist LinkType = "playlist"
+	LinkTypeArtist   LinkType = "artist"
+	LinkTypeEpisode  LinkType = "episode"
+	LinkTypeShow     LinkType = "show"
+	LinkTypeShort    LinkType = "short" // spotify.link short link, must be resolved first
+)
+
+// Link is a Spotify link or URI found in a request
+type Link struct {
+	Type LinkType
+	ID   string // Empty for short links
+	URL  string // The link as it appeared in the text
+}
+
+var (
+	// open.spotify.com links, with optional scheme, intl-xx locale prefix and embed path
+	spotifyURLRegex = regexp.MustCompile(`(?i)(?:https?://)?open\.spotify\.com/(?:intl-[a-z]{2}(?:-[a-z]{2})?/)?(?:embed/)?(track|album|playlist|artist|episode|show)/([0-9A-Za-z]{22})`)
+	// spotify:track:<id> style URIs
+	spotifyURIRegex = regexp.MustCompile(`(?i)spotify:(track|album|playlist|artist|episode|show):([0-9A-Za-z]{22})`)
+	// spotify.link and spoti.fi short links
+	spotifyShortLinkRegex = regexp.MustCompile(`(?i)(?:https?://)?(?:spotify\.link|spoti\.fi)/[0-9A-Za-z_-]+`)
+)
+
+// shortLinkClient follows short link redirects
+var shortLinkClient = &http.Client{Timeout: 10 * time.Second}
+
+// maxShortLinkBody limits how much of a short link landing page is scanned for the target link
+const maxShortLinkBody = 256 * 1024
+
+// FindSpotifyLink returns the first Spotify link or URI in the text, or nil if there is none
+func FindSpotifyLink(text string) *Link {
+	found, foundAt := findContentLink(text)
+
+	if match := spotifyShortLinkRegex.FindStringIndex(text); match != nil && (found == nil || match[0] < foundAt) {
+		found = &Link{
+			Type: LinkTypeShort,
+			URL:  text[match[0]:match[1]],
+		}
+	}
+
+	return found
+}
+
+// findContentLink returns the first open.spotify.com link or Spotify URI in the text and its offset
+func findContentLink(text string) (*Link, int) {
+	var found *Link
+	foundAt := -1
+
+	for _, pattern := range []*regexp.Regexp{spotifyURLRegex, spotifyURIRegex} {
+		if match := pattern.FindStringSubmatchIndex(text); match != nil && (found == nil || match[0] < foundAt) {
+			found = &Link{
+				Type: LinkType(strings.ToLower(text[match[2]:match[3]])),
+				ID:   text[match[4]:match[5]],
+				URL:  text[match[0]:match[1]],
+			}
+			foundAt = match[0]
+		}
+	}
+
+	return found, foundAt
+}
+
+// ResolveShortLink follows a spotify.link short link to the link it points to
+func ResolveShortLink(shortURL string) (*Link, error) {
+	if !spotifyShortLinkRegex.MatchString(shortURL) {
+		return nil, fmt.Errorf("not a Spotify short link: %s", shortURL)
+	}
+	if lower := strings.ToLower(shortURL); !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		shortURL = "https://" + shortURL
+	}
+
+	resp, err := shortLinkClient.Get(shortURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve short link %s: %w", shortURL, err)
+	}
+	defer resp.Body.Close()
+
+	// Short links usually redirect straight to open.spotify.com
+	if link, _ := findContentLink(resp.Request.URL.String()); link != nil {
+		return link, nil
+	}
+
+	// Otherwise they land on a page that embeds the target link
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxShortLinkBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read short link page %s: %w", shortURL, err)
+	}
+	if link, _ := findContentLink(string(body)); link != nil {
+		return link, nil
+	}
+
+	return nil, fmt.Errorf("short link %s does not point to Spotify content", shortURL)
+}
@@ -0,0 +1,165 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// PlaylistRefreshInterval is how long cached playlist contents are trusted before they are fetched again
+const PlaylistRefreshInterval = 30 * time.Minute
+
+// PlaylistCache keeps the contents of allowlisted playlists
+type PlaylistCache struct {
+	store map[spotify.ID]*cachedPlaylist
+	mutex sync.RWMutex
+}
+
+type cachedPlaylist struct {
+	name      string
+	trackIDs  map[spotify.ID]bool
+	isrcs     map[string]bool // Matches relinked copies of the same recording
+	fetchedAt time.Time
+}
+
+// NewPlaylistCache creates an empty playlist cache
+func NewPlaylistCache() *PlaylistCache {
+	return &PlaylistCache{
+		store: make(map[spotify.ID]*cachedPlaylist),
+	}
+}
+
+// get returns a cached playlist that is still fresh, or nil
+func (pc *PlaylistCache) get(id spotify.ID) *cachedPlaylist {
+	pc.mutex.RLock()
+	defer pc.mutex.RUnlock()
+
+	playlist, exists := pc.store[id]
+	if !exists || time.Since(playlist.fetchedAt) >= PlaylistRefreshInterval {
+		return nil
+	}
+	return playlist
+}
+
+// set stores the contents of a playlist
+func (pc *PlaylistCache) set(id spotify.ID, playlist *cachedPlaylist) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	pc.store[id] = playlist
+}
+
+// Invalidate drops a playlist from the cache
+func (pc *PlaylistCache) Invalidate(id spotify.ID) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	delete(pc.store, id)
+}
+
+// Global playlist cache instance
+var GlobalPlaylistCache = NewPlaylistCache()
+
+// GetPlaylistName returns the name of a playlist
+func (s *SpotifyClient) GetPlaylistName(playlistID spotify.ID) (string, error) {
+	playlist, err := s.loadPlaylist(playlistID)
+	if err != nil {
+		return "", err
+	}
+	return playlist.name, nil
+}
+
+// PlaylistContains checks if a track is in a playlist, fetching the playlist if it isn't cached or is stale
+func (s *SpotifyClient) PlaylistContains(playlistID spotify.ID, track *spotify.FullTrack) (bool, error) {
+	playlist, err := s.loadPlaylist(playlistID)
+	if err != nil {
+		return false, err
+	}
+
+	if playlist.trackIDs[track.ID] {
+		return true, nil
+	}
+	if isrc := track.ExternalIDs["isrc"]; isrc != "" && playlist.isrcs[isrc] {
+		return true, nil
+	}
+	return false, nil
+}
+
+// RefreshPlaylists fetches the playlists whose cached contents are stale
+func (s *SpotifyClient) RefreshPlaylists(playlistIDs []spotify.ID) error {
+	var errs []error
+	for _, id := range playlistIDs {
+		if _, err := s.loadPlaylist(id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadPlaylist returns the cached contents of a playlist, fetching them when needed
+func (s *SpotifyClient) loadPlaylist(playlistID spotify.ID) (*cachedPlaylist, error) {
+	if playlist := GlobalPlaylistCache.get(playlistID); playlist != nil {
+		return playlist, nil
+	}
+
+	playlist, err := s.fetchPlaylist(playlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	GlobalPlaylistCache.set(playlistID, playlist)
+	return playlist, nil
+}
+
+// fetchPlaylist reads all tracks of a playlist from Spotify
+func (s *SpotifyClient) fetchPlaylist(playlistID spotify.ID) (*cachedPlaylist, error) {
+	ctx := context.Background()
+
+	var info *spotify.FullPlaylist
+	err := s.executeWithRetry(func() error {
+		var err error
+		info, err = s.client.GetPlaylist(ctx, playlistID, spotify.Fields("name"))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlist %s: %w", playlistID, err)
+	}
+
+	playlist := &cachedPlaylist{
+		name:      info.Name,
+		trackIDs:  make(map[spotify.ID]bool),
+		isrcs:     make(map[string]bool),
+		fetchedAt: time.Now(),
+	}
+
+	var page *spotify.PlaylistItemPage
+	err = s.executeWithRetry(func() error {
+		var err error
+		page, err = s.client.GetPlaylistItems(ctx, playlistID, spotify.Limit(100))
+		return err
+	})
+	for err == nil {
+		for _, item := range page.Items {
+			if item.Track.Track == nil {
+				continue // Episodes and unavailable tracks
+			}
+			playlist.trackIDs[item.Track.Track.ID] = true
+			if isrc := item.Track.Track.ExternalIDs["isrc"]; isrc != "" {
+				playlist.isrcs[isrc] = true
+			}
+		}
+
+		err = s.executeWithRetry(func() error {
+			return s.client.NextPage(ctx, page)
+		})
+	}
+	if !errors.Is(err, spotify.ErrNoMorePages) {
+		return nil, fmt.Errorf("failed to get tracks of playlist %s: %w", playlistID, err)
+	}
+
+	return playlist, nil
+}
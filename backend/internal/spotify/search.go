@@ -0,0 +1,280 @@
+package spotify
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// SearchCandidateLimit is how many tracks are fetched and scored per search
+const SearchCandidateLimit = 10
+
+// Score weights
+const (
+	scoreArtistExact   = 40
+	scoreArtistPartial = 20
+	scoreArtistMissing = -30
+	scoreTitleExact    = 40
+	scoreTitlePartial  = 20
+	scoreWordCoverage  = 30 // Scaled by the share of query words found, for queries without "artist - title"
+	scoreVersion       = -50
+	scorePopularityMax = 10
+)
+
+// strongMatchScore is the score of a track matching both the artist and the title of an "artist - title"
+// query. Weaker results make SearchTrack try more queries.
+const strongMatchScore = scoreArtistExact + scoreTitleExact
+
+// versionKeywords mark alternative versions that are only wanted when asked for
+var versionKeywords = []string{
+	"karaoke",
+	"cover",
+	"remix",
+	"instrumental",
+	"sped up",
+	"speed up",
+	"slowed",
+	"nightcore",
+	"tribute",
+	"made famous",
+	"originally performed",
+}
+
+// titleSeparators split "artist - title" queries
+var titleSeparators = []string{" - ", " – ", " — "}
+
+// SearchQuery is a song request query split into its parts
+type SearchQuery struct {
+	Raw    string
+	Artist string // Left side of an "artist - title" query, empty for free text
+	Title  string // Right side of an "artist - title" query, or the whole free-text query
+}
+
+// ParseSearchQuery splits an "artist - title" query. Other queries are kept as a free-text title.
+// Viewers also write "title - artist", so the parts are only a guess; ScoreTrack tries both orientations.
+func ParseSearchQuery(query string) SearchQuery {
+	query = strings.TrimSpace(query)
+	for _, separator := range titleSeparators {
+		if artist, title, found := strings.Cut(query, separator); found {
+			artist = strings.TrimSpace(artist)
+			title = strings.TrimSpace(title)
+			if artist != "" && title != "" {
+				return SearchQuery{Raw: query, Artist: artist, Title: title}
+			}
+		}
+	}
+	return SearchQuery{Raw: query, Title: query}
+}
+
+// SpotifyQuery returns the query string sent to the Spotify search API
+func (q SearchQuery) SpotifyQuery() string {
+	if q.Artist == "" {
+		return q.Raw
+	}
+	return "artist:" + q.Artist + " track:" + q.Title
+}
+
+// Swapped returns the query read as "title - artist"
+func (q SearchQuery) Swapped() SearchQuery {
+	if q.Artist == "" {
+		return q
+	}
+	return SearchQuery{Raw: q.Raw, Artist: q.Title, Title: q.Artist}
+}
+
+// mergeTracks appends the tracks that aren't in the list yet
+func mergeTracks(tracks, more []spotify.FullTrack) []spotify.FullTrack {
+	seen := make(map[spotify.ID]bool)
+	for _, track := range tracks {
+		seen[track.ID] = true
+	}
+	for _, track := range more {
+		if !seen[track.ID] {
+			seen[track.ID] = true
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks
+}
+
+// isStrongMatch checks if the best of the tracks matches the query well enough to stop searching
+func isStrongMatch(q SearchQuery, tracks []spotify.FullTrack) bool {
+	best := BestTrack(q, tracks)
+	return best != nil && ScoreTrack(q, best) >= strongMatchScore
+}
+
+// BestTrack returns the highest scoring track, preferring Spotify's order on ties.
+// Returns nil if there are no tracks.
+func BestTrack(q SearchQuery, tracks []spotify.FullTrack) *spotify.FullTrack {
+	var best *spotify.FullTrack
+	bestScore := 0
+	for i := range tracks {
+		score := ScoreTrack(q, &tracks[i])
+		if best == nil || score > bestScore {
+			best = &tracks[i]
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// CleanVersion returns the best non-explicit candidate that is the same song as the explicit track.
+// Returns nil if there is none.
+func CleanVersion(track *spotify.FullTrack, candidates []spotify.FullTrack) *spotify.FullTrack {
+	if len(track.Artists) == 0 {
+		return nil
+	}
+
+	q := SearchQuery{
+		Raw:    track.Artists[0].Name + " - " + track.Name,
+		Artist: track.Artists[0].Name,
+		Title:  stripVersionInfo(track.Name),
+	}
+	wantedTitle := normalizeText(q.Title)
+	wantedArtist := normalizeText(q.Artist)
+
+	var clean []spotify.FullTrack
+	for _, candidate := range candidates {
+		if candidate.Explicit || candidate.ID == track.ID || normalizeText(stripVersionInfo(candidate.Name)) != wantedTitle {
+			continue
+		}
+		for _, artist := range candidate.Artists {
+			if normalizeText(artist.Name) == wantedArtist {
+				clean = append(clean, candidate)
+				break
+			}
+		}
+	}
+
+	return BestTrack(q, clean)
+}
+
+// ScoreTrack rates how well a track matches a request query
+func ScoreTrack(q SearchQuery, track *spotify.FullTrack) int {
+	score := 0
+
+	title := normalizeText(track.Name)
+	baseTitle := normalizeText(stripVersionInfo(track.Name))
+	var artists []string
+	for _, artist := range track.Artists {
+		artists = append(artists, normalizeText(artist.Name))
+	}
+
+	if q.Artist != "" {
+		// Keep the better of "artist - title" and "title - artist"
+		left, right := normalizeText(q.Artist), normalizeText(q.Title)
+		score += max(
+			scoreArtist(left, artists)+scoreTitle(right, title, baseTitle),
+			scoreArtist(right, artists)+scoreTitle(left, title, baseTitle),
+		)
+	} else {
+		wanted := normalizeText(q.Title)
+		score += scoreTitle(wanted, title, baseTitle)
+		score += scoreWordCoverage * wordCoverage(wanted, title+" "+strings.Join(artists, " ")) / 100
+	}
+
+	// Penalize karaoke, covers and the like unless the query asks for them
+	query := normalizeText(q.Raw)
+	candidate := title + " " + normalizeText(track.Album.Name) + " " + strings.Join(artists, " ")
+	for _, keyword := range versionKeywords {
+		if containsPhrase(candidate, keyword) && !containsPhrase(query, keyword) {
+			score += scoreVersion
+		}
+	}
+
+	score += int(track.Popularity) * scorePopularityMax / 100
+	return score
+}
+
+// scoreArtist rates how well the requested artist matches the track's artists
+func scoreArtist(wanted string, artists []string) int {
+	best := scoreArtistMissing
+	for _, artist := range artists {
+		switch {
+		case artist == wanted:
+			return scoreArtistExact
+		case artist != "" && (strings.Contains(artist, wanted) || strings.Contains(wanted, artist)):
+			best = scoreArtistPartial
+		}
+	}
+	return best
+}
+
+// scoreTitle rates how well the requested title matches the track title
+func scoreTitle(wanted, title, baseTitle string) int {
+	switch {
+	case wanted == "":
+		return 0
+	case wanted == title || wanted == baseTitle:
+		return scoreTitleExact
+	case strings.Contains(title, wanted) || (baseTitle != "" && strings.Contains(wanted, baseTitle)):
+		return scoreTitlePartial
+	default:
+		return 0
+	}
+}
+
+// wordCoverage returns the percentage of query words that appear in the text
+func wordCoverage(query, text string) int {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return 0
+	}
+
+	available := make(map[string]bool)
+	for _, word := range strings.Fields(text) {
+		available[word] = true
+	}
+
+	found := 0
+	for _, word := range words {
+		if available[word] {
+			found++
+		}
+	}
+	return found * 100 / len(words)
+}
+
+// containsPhrase checks if normalized text contains a phrase as whole words
+func containsPhrase(text, phrase string) bool {
+	return strings.Contains(" "+text+" ", " "+phrase+" ")
+}
+
+// stripVersionInfo removes "(feat. ...)", "[...]" and " - Remastered ..." suffixes from a track title
+func stripVersionInfo(title string) string {
+	if index := strings.Index(title, " - "); index > 0 {
+		title = title[:index]
+	}
+	for _, brackets := range []string{"()", "[]"} {
+		for {
+			open := strings.IndexByte(title, brackets[0])
+			if open < 0 {
+				break
+			}
+			end := strings.IndexByte(title[open:], brackets[1])
+			if end < 0 {
+				title = title[:open]
+				break
+			}
+			title = title[:open] + title[open+end+1:]
+		}
+	}
+	return title
+}
+
+// normalizeText lowercases text and reduces punctuation to single spaces
+func normalizeText(text string) string {
+	var builder strings.Builder
+	space := true
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+			space = false
+		} else if r != '\'' && !space {
+			builder.WriteRune(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(builder.String())
+}
@@ -0,0 +1,181 @@
+package spotify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// The fixtures in testdata are hand-written search responses in the Spotify API format, trimmed to the
+// fields the scoring reads. Their tracks only need to cover the scoring cases and aren't checked against
+// Spotify's catalogue.
+
+// Track IDs in the search fixtures
+const (
+	bohemianSpedUp   = "TxO9xLp68TWHWxd7uehIxM"
+	bohemianKaraoke  = "KzvTcgUOQ5leQwXceMQrXZ"
+	bohemianCover    = "3zX8mLq0aPbV2rTn6YwKcE"
+	bohemianOriginal = "4u7EnebtmKWzUH433cf5Qv"
+
+	blindingLightsPiano = "9pLkJ3hGfD2sA1qWeRtY6u"
+	blindingLights      = "0VjIjW4GlUZAMYd2vXMi3b"
+	saveYourTears       = "5QO79kh1waicV47BqGRL3g"
+)
+
+// readSearchFixture reads a Spotify search response from testdata
+func readSearchFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "search_"+name+".json"))
+	if err != nil {
+		t.Fatalf("reading fixture %s: %v", name, err)
+	}
+	return data
+}
+
+// loadSearchFixture reads the tracks of a Spotify search response from testdata
+func loadSearchFixture(t *testing.T, name string) []spotify.FullTrack {
+	t.Helper()
+
+	var result spotify.SearchResult
+	if err := json.Unmarshal(readSearchFixture(t, name), &result); err != nil {
+		t.Fatalf("parsing fixture %s: %v", name, err)
+	}
+	if result.Tracks == nil {
+		t.Fatalf("fixture %s has no tracks", name)
+	}
+	return result.Tracks.Tracks
+}
+
+func TestBestTrack(t *testing.T) {
+	tests := []struct {
+		name       string
+		fixture    string
+		query      string
+		candidates []spotify.ID // Subset of the fixture to score; all tracks when empty
+		want       spotify.ID
+	}{
+		// The versions come first and are at least as popular, so only the penalty keeps them out
+		{"sped up is penalized", "bohemian_rhapsody", "bohemian rhapsody", []spotify.ID{bohemianSpedUp, bohemianOriginal}, bohemianOriginal},
+		{"karaoke is penalized", "bohemian_rhapsody", "bohemian rhapsody", []spotify.ID{bohemianKaraoke, bohemianOriginal}, bohemianOriginal},
+		{"cover is penalized", "bohemian_rhapsody", "bohemian rhapsody", []spotify.ID{bohemianCover, bohemianOriginal}, bohemianOriginal},
+		{"sped up when asked for", "bohemian_rhapsody", "bohemian rhapsody sped up", nil, bohemianSpedUp},
+		{"karaoke when asked for", "bohemian_rhapsody", "bohemian rhapsody karaoke", nil, bohemianKaraoke},
+		{"artist - title", "bohemian_rhapsody", "Queen - Bohemian Rhapsody", nil, bohemianOriginal},
+		{"title - artist", "bohemian_rhapsody", "Bohemian Rhapsody - Queen", nil, bohemianOriginal},
+
+		{"artist match beats popularity", "the_weeknd", "Midnight Keys - Blinding Lights", nil, blindingLightsPiano},
+		{"artist match", "the_weeknd", "The Weeknd - Blinding Lights", nil, blindingLights},
+		{"title - artist match", "the_weeknd", "Blinding Lights - The Weeknd", nil, blindingLights},
+		{"title match", "the_weeknd", "The Weeknd - Save Your Tears", nil, saveYourTears},
+		{"title match reversed", "the_weeknd", "Save Your Tears - The Weeknd", nil, saveYourTears},
+		{"free text", "the_weeknd", "weeknd blinding lights", nil, blindingLights},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracks := loadSearchFixture(t, tt.fixture)
+			if len(tt.candidates) > 0 {
+				var subset []spotify.FullTrack
+				for _, id := range tt.candidates {
+					for _, track := range tracks {
+						if track.ID == id {
+							subset = append(subset, track)
+						}
+					}
+				}
+				tracks = subset
+			}
+
+			best := BestTrack(ParseSearchQuery(tt.query), tracks)
+			if best == nil {
+				t.Fatalf("BestTrack(%q) = nil, want %s", tt.query, tt.want)
+			}
+			if best.ID != tt.want {
+				t.Errorf("BestTrack(%q) = %s (%s), want %s", tt.query, best.ID, best.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchTrack(t *testing.T) {
+	// Search responses by query; anything else finds nothing
+	responses := map[string]string{
+		"artist:Queen track:Bohemian Rhapsody": "bohemian_rhapsody",
+		"artist:Weeknd track:Blinding Lights":  "midnight_keys",
+		"Weeknd - Blinding Lights":             "the_weeknd",
+		"blinding lights":                      "the_weeknd",
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		wantQueries []string
+		want        spotify.ID
+	}{
+		{
+			name:        "artist - title",
+			query:       "Queen - Bohemian Rhapsody",
+			wantQueries: []string{"artist:Queen track:Bohemian Rhapsody"},
+			want:        bohemianOriginal,
+		},
+		{
+			name:        "title - artist uses swapped fields",
+			query:       "Bohemian Rhapsody - Queen",
+			wantQueries: []string{"artist:Bohemian Rhapsody track:Queen", "artist:Queen track:Bohemian Rhapsody"},
+			want:        bohemianOriginal,
+		},
+		{
+			name:        "weak match falls back to free text",
+			query:       "Weeknd - Blinding Lights",
+			wantQueries: []string{"artist:Weeknd track:Blinding Lights", "artist:Blinding Lights track:Weeknd", "Weeknd - Blinding Lights"},
+			want:        blindingLights,
+		},
+		{
+			name:        "free text",
+			query:       "blinding lights",
+			wantQueries: []string{"blinding lights"},
+			want:        blindingLights,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/search" || r.URL.Query().Get("type") != "track" {
+					t.Errorf("unexpected request %s", r.URL)
+				}
+				query := r.URL.Query().Get("q")
+				queries = append(queries, query)
+
+				w.Header().Set("Content-Type", "application/json")
+				if fixture, ok := responses[query]; ok {
+					w.Write(readSearchFixture(t, fixture))
+					return
+				}
+				w.Write([]byte(`{"tracks": {"items": [], "total": 0}}`))
+			}))
+			defer server.Close()
+
+			client := &SpotifyClient{client: spotify.New(server.Client(), spotify.WithBaseURL(server.URL+"/"))}
+			track, err := client.SearchTrack(tt.query)
+			if err != nil {
+				t.Fatalf("SearchTrack(%q): %v", tt.query, err)
+			}
+
+			if !slices.Equal(queries, tt.wantQueries) {
+				t.Errorf("SearchTrack(%q) searched %q, want %q", tt.query, queries, tt.wantQueries)
+			}
+			if track == nil || track.ID != tt.want {
+				t.Errorf("SearchTrack(%q) = %v, want %s", tt.query, track, tt.want)
+			}
+		})
+	}
+}
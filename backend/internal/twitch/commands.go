@@ -0,0 +1,180 @@
+package twitch
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"github.com/emcifuntik/twitch-spotify-request/internal/spotify"
+	"gorm.io/gorm"
+)
+
+// builtinCommandTypes maps the built-in chat commands (without "!") to the configurable command type
+// that does the same thing, or an empty string if none does
+var builtinCommandTypes = map[string]string{
+	strings.TrimPrefix(string(ChatCommandSongQueue), "!"):   db.CommandTypeQueue,
+	strings.TrimPrefix(string(ChatCommandSongCurrent), "!"): "",
+	strings.TrimPrefix(string(ChatCommandSongsRecent), "!"): "",
+	strings.TrimPrefix(string(ChatCommandSongVolume), "!"):  db.CommandTypeVolume,
+	strings.TrimPrefix(string(ChatCommandSongHelp), "!"):    "",
+}
+
+// IsReservedCommandName checks if a configured command name would hide a built-in command that does something else
+func IsReservedCommandName(name, commandType string) bool {
+	builtinType, exists := builtinCommandTypes[strings.ToLower(strings.TrimPrefix(name, "!"))]
+	return exists && builtinType != commandType
+}
+
+// handleConfiguredCommand dispatches a chat command using the streamer's configured commands.
+// Returns false if no enabled command matches, so the built-in commands can handle it.
+func (rl *RewardListener) handleConfiguredCommand(user *Chatter, command, args string) bool {
+	name := strings.TrimPrefix(command, "!")
+	if name == "" {
+		return false
+	}
+
+	database := db.GetDB()
+	if database == nil {
+		log.Printf("Database not available for command lookup")
+		return false
+	}
+
+	cmd, err := db.GetEnabledCommandByName(database, rl.streamer.ID, name)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Error looking up command %s for streamer %d: %v", name, rl.streamer.ID, err)
+		}
+		return false
+	}
+
+	// Built-in commands win over configured ones that would hide them
+	if IsReservedCommandName(cmd.Name, cmd.Type) {
+		log.Printf("Command %s of type %s collides with a built-in command, ignoring it", command, cmd.Type)
+		return false
+	}
+
+	log.Printf("Command %s resolved to type %s for streamer %d", command, cmd.Type, rl.streamer.ID)
+
+	permission := cmd.EffectivePermission()
+	if !rl.hasPermission(user, roleFromPermission(permission)) {
+		log.Printf("Command %s denied for user %s: requires %s", command, user.Name, permission)
+		rl.sendMessage(fmt.Sprintf("@%s %s", user.Name, permissionDeniedMessage(permission)))
+		return true
+	}
+
+	switch cmd.Type {
+	case db.CommandTypeRequest:
+		rl.handleRequestCommand(user, command, args)
+	case db.CommandTypeSkip:
+		rl.handleSkipCommand(user.Name, command)
+	case db.CommandTypeBlock:
+		rl.handleBlockCommand(user.Name, command, args)
+	case db.CommandTypeVolume:
+		rl.handleVolumeCommand(user.Name, args)
+	case db.CommandTypeQueue:
+		rl.handleSongQueue(user.Name)
+	default:
+		log.Printf("Unknown command type %s for command %s", cmd.Type, command)
+		return false
+	}
+
+	return true
+}
+
+// handleRequestCommand runs a chat song request through the request pipeline
+func (rl *RewardListener) handleRequestCommand(user *Chatter, command, args string) {
+	if !rl.streamer.UseCommands {
+		rl.sendMessage(fmt.Sprintf("@%s Song requests are taken through channel points on this channel", user.Name))
+		return
+	}
+
+	if args == "" {
+		rl.sendMessage(fmt.Sprintf("@%s Usage: %s <artist - song or Spotify link>", user.Name, command))
+		return
+	}
+
+	if err := rl.handleSongRequest(&songRequest{
+		user:   user,
+		query:  args,
+		source: db.RequestSourceChat,
+	}); err != nil {
+		log.Printf("Error handling chat song request from %s: %v", user.Name, err)
+	}
+}
+
+// handleSkipCommand skips the current track
+func (rl *RewardListener) handleSkipCommand(userName, command string) {
+	if err := rl.skipCurrentTrack(fmt.Sprintf("skipped by %s via %s", userName, command)); err != nil {
+		log.Printf("Error skipping track: %v", err)
+		rl.sendMessage(fmt.Sprintf("@%s Error skipping track", userName))
+		return
+	}
+
+	rl.sendMessage(fmt.Sprintf("@%s Track skipped", userName))
+}
+
+// handleBlockCommand blocks the currently playing track or its artists.
+// Usage: !block [song|artist] [reason]
+func (rl *RewardListener) handleBlockCommand(userName, command, args string) {
+	target, reason, _ := strings.Cut(strings.TrimSpace(args), " ")
+	target = strings.ToLower(target)
+	reason = strings.TrimSpace(reason)
+
+	switch target {
+	case "", "song", "track":
+		target = "song"
+	case "artist":
+	default:
+		rl.sendMessage(fmt.Sprintf("@%s Usage: %s [song|artist] [reason]", userName, command))
+		return
+	}
+
+	currentTrack, err := rl.spotifyClient.GetCurrentTrack()
+	if err != nil || currentTrack.Item == nil {
+		rl.sendMessage(fmt.Sprintf("@%s Nothing is playing right now", userName))
+		return
+	}
+
+	database := db.GetDB()
+	if database == nil {
+		rl.sendMessage(fmt.Sprintf("@%s Error blocking %s", userName, target))
+		return
+	}
+
+	opts := db.BlockOptions{Reason: reason, AddedBy: userName}
+	track := currentTrack.Item
+
+	var blocked string
+	if target == "artist" {
+		var names []string
+		for _, artist := range track.Artists {
+			if err := db.AddBlock(database, rl.streamer.ID, db.BlockTypeArtist, string(artist.ID), artist.Name, opts); err != nil {
+				log.Printf("Error blocking artist %s: %v", artist.ID, err)
+				rl.sendMessage(fmt.Sprintf("@%s Error blocking artist", userName))
+				return
+			}
+			names = append(names, artist.Name)
+		}
+		blocked = "artist: " + strings.Join(names, ", ")
+	} else {
+		songName := spotify.SongItemToReadable(track)
+		if err := db.AddBlock(database, rl.streamer.ID, db.BlockTypeTrack, string(track.ID), songName, opts); err != nil {
+			log.Printf("Error blocking track %s: %v", track.ID, err)
+			rl.sendMessage(fmt.Sprintf("@%s Error blocking track", userName))
+			return
+		}
+		blocked = "track: " + songName
+	}
+
+	if db.IsBlockCommandSkipEnabled(database, rl.streamer.ID) {
+		if err := rl.skipCurrentTrack(fmt.Sprintf("blocked by %s via %s", userName, command)); err != nil {
+			log.Printf("Error skipping blocked track %s: %v", track.ID, err)
+		} else {
+			blocked += " (skipped)"
+		}
+	}
+
+	rl.sendMessage(fmt.Sprintf("@%s Blocked %s", userName, blocked))
+}
@@ -1,110 +1,162 @@
 package twitch
 
 import (
+	"fmt"
+	"log"
 	"sync"
 	"time"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	spotifylib "github.com/zmb3/spotify/v2"
 )
 
-// CooldownManager manages cooldowns for songs per streamer
+// cooldownRetention is how long started cooldowns are kept; longer configured cooldowns end after it
+const cooldownRetention = 7 * 24 * time.Hour
+
+// CooldownManager manages the track, artist, album and viewer cooldowns of all streamers.
+// Cooldowns are stored in the database so they survive restarts.
 type CooldownManager struct {
-	cooldowns map[string]map[string]time.Time // streamerID -> trackURI -> last played time
-	mutex     sync.RWMutex
+	cleanupOnce sync.Once
 }
 
-var globalCooldownManager = &CooldownManager{
-	cooldowns: make(map[string]map[string]time.Time),
+// CooldownBlock describes the cooldown rule that blocks a request
+type CooldownBlock struct {
+	Kind      string // db.CooldownKind*
+	Name      string // Readable name of the subject on cooldown
+	Length    int    // Configured cooldown in seconds
+	Remaining int    // Seconds until the cooldown ends
 }
 
+var globalCooldownManager = &CooldownManager{}
+
 // GetCooldownManager returns the global cooldown manager
 func GetCooldownManager() *CooldownManager {
 	return globalCooldownManager
 }
 
-// AddCooldown adds a cooldown for a track for a specific streamer
-func (cm *CooldownManager) AddCooldown(streamerID, trackURI string) {
-	cm.mutex.Lock()
-	defer cm.mutex.Unlock()
-
-	if cm.cooldowns[streamerID] == nil {
-		cm.cooldowns[streamerID] = make(map[string]time.Time)
+// trackCooldownSubjects returns the track, artist and album cooldown subjects of a track
+func trackCooldownSubjects(track *spotifylib.FullTrack) []db.CooldownSubject {
+	subjects := []db.CooldownSubject{{Kind: db.CooldownKindTrack, Key: string(track.ID), Name: track.Name}}
+	for _, artist := range track.Artists {
+		subjects = append(subjects, db.CooldownSubject{Kind: db.CooldownKindArtist, Key: string(artist.ID), Name: artist.Name})
 	}
-
-	cm.cooldowns[streamerID][trackURI] = time.Now()
+	if track.Album.ID != "" {
+		subjects = append(subjects, db.CooldownSubject{Kind: db.CooldownKindAlbum, Key: string(track.Album.ID), Name: track.Album.Name})
+	}
+	return subjects
 }
 
-// IsOnCooldown checks if a track is on cooldown for a specific streamer
-func (cm *CooldownManager) IsOnCooldown(streamerID, trackURI string, cooldownSeconds int) bool {
-	cm.mutex.RLock()
-	defer cm.mutex.RUnlock()
+// userCooldownSubject returns the cooldown subject of a viewer
+func userCooldownSubject(user *Chatter) db.CooldownSubject {
+	return db.CooldownSubject{Kind: db.CooldownKindUser, Key: user.ID, Name: user.Name}
+}
 
-	streamerCooldowns, exists := cm.cooldowns[streamerID]
-	if !exists {
-		return false
+// AddCooldown starts the cooldowns of the given subjects
+func (cm *CooldownManager) AddCooldown(streamerID uint, subjects []db.CooldownSubject) {
+	database := db.GetDB()
+	if database == nil {
+		return
 	}
 
-	lastPlayed, exists := streamerCooldowns[trackURI]
-	if !exists {
-		return false
+	if err := db.StartCooldowns(database, streamerID, subjects); err != nil {
+		log.Printf("Error starting cooldowns: %v", err)
 	}
-
-	return time.Since(lastPlayed).Seconds() < float64(cooldownSeconds)
 }
 
-// GetRemainingCooldown returns the remaining cooldown time in seconds
-func (cm *CooldownManager) GetRemainingCooldown(streamerID, trackURI string, cooldownSeconds int) int {
-	cm.mutex.RLock()
-	defer cm.mutex.RUnlock()
-
-	streamerCooldowns, exists := cm.cooldowns[streamerID]
-	if !exists {
-		return 0
+// GetRemainingCooldown returns the cooldown rule with the longest remaining time that blocks any of the subjects,
+// or nil if none does. Cooldowns of kinds the streamer has no length configured for are ignored.
+func (cm *CooldownManager) GetRemainingCooldown(streamerID uint, subjects []db.CooldownSubject) *CooldownBlock {
+	database := db.GetDB()
+	if database == nil {
+		return nil
 	}
 
-	lastPlayed, exists := streamerCooldowns[trackURI]
-	if !exists {
-		return 0
+	lengths := db.GetCooldownLengths(database, streamerID)
+
+	var active []db.CooldownSubject
+	for _, subject := range subjects {
+		if lengths[subject.Kind] > 0 {
+			active = append(active, subject)
+		}
+	}
+	if len(active) == 0 {
+		return nil
 	}
 
-	elapsed := time.Since(lastPlayed).Seconds()
-	remaining := float64(cooldownSeconds) - elapsed
+	cooldowns, err := db.GetCooldowns(database, streamerID, active)
+	if err != nil {
+		log.Printf("Error checking cooldowns: %v", err)
+		return nil
+	}
 
-	if remaining <= 0 {
-		return 0
+	// Track cooldowns also run from when the track actually last played on the channel
+	for _, subject := range active {
+		if subject.Kind != db.CooldownKindTrack {
+			continue
+		}
+		played, err := db.GetPlayedTrack(database, streamerID, subject.Key)
+		if err != nil {
+			log.Printf("Error checking played ledger: %v", err)
+		} else if played != nil {
+			cooldowns = append(cooldowns, db.Cooldown{Kind: subject.Kind, Key: subject.Key, Name: subject.Name, StartedAt: played.PlayedAt})
+		}
 	}
 
-	return int(remaining)
+	var block *CooldownBlock
+	for _, cooldown := range cooldowns {
+		length := lengths[cooldown.Kind]
+		remaining := int(time.Until(cooldown.StartedAt.Add(time.Duration(length) * time.Second)).Seconds())
+		if remaining <= 0 || (block != nil && remaining <= block.Remaining) {
+			continue
+		}
+		block = &CooldownBlock{
+			Kind:      cooldown.Kind,
+			Name:      cooldown.Name,
+			Length:    length,
+			Remaining: remaining,
+		}
+	}
+	return block
 }
 
-// CleanupExpiredCooldowns removes expired cooldowns to prevent memory leaks
+// CleanupExpiredCooldowns removes cooldowns older than the retention period
 func (cm *CooldownManager) CleanupExpiredCooldowns() {
-	cm.mutex.Lock()
-	defer cm.mutex.Unlock()
-
-	now := time.Now()
-	maxCooldown := 24 * time.Hour // Remove cooldowns older than 24 hours
-
-	for streamerID, streamerCooldowns := range cm.cooldowns {
-		for trackURI, lastPlayed := range streamerCooldowns {
-			if now.Sub(lastPlayed) > maxCooldown {
-				delete(streamerCooldowns, trackURI)
-			}
-		}
+	database := db.GetDB()
+	if database == nil {
+		return
+	}
 
-		// If no cooldowns left for this streamer, remove the map
-		if len(streamerCooldowns) == 0 {
-			delete(cm.cooldowns, streamerID)
-		}
+	if _, err := db.DeleteCooldownsBefore(database, time.Now().Add(-cooldownRetention)); err != nil {
+		log.Printf("Error cleaning up cooldowns: %v", err)
 	}
 }
 
-// StartPeriodicCleanup starts a goroutine that periodically cleans up expired cooldowns
+// StartPeriodicCleanup starts a goroutine that periodically cleans up expired cooldowns. Only the first call starts it.
 func (cm *CooldownManager) StartPeriodicCleanup() {
-	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
+	cm.cleanupOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(1 * time.Hour)
+			defer ticker.Stop()
 
-		for range ticker.C {
-			cm.CleanupExpiredCooldowns()
-		}
-	}()
+			for range ticker.C {
+				cm.CleanupExpiredCooldowns()
+			}
+		}()
+	})
+}
+
+// cooldownMessage returns the chat reply for a request blocked by a cooldown
+func cooldownMessage(block *CooldownBlock) string {
+	remaining := formatDuration(block.Remaining + 1)
+
+	switch block.Kind {
+	case db.CooldownKindArtist:
+		return fmt.Sprintf("%s недавно заказывали, следующий трек этого исполнителя можно через %s", block.Name, remaining)
+	case db.CooldownKindAlbum:
+		return fmt.Sprintf("с альбома %s недавно заказывали, следующий трек с него можно через %s", block.Name, remaining)
+	case db.CooldownKindUser:
+		return fmt.Sprintf("слишком часто, следующий заказ можно через %s", remaining)
+	default:
+		return fmt.Sprintf("этот трек недавно играл, повторить можно через %s", remaining)
+	}
 }
@@ -0,0 +1,267 @@
+package twitch
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+)
+
+// EventSub request headers, see https://dev.twitch.tv/docs/eventsub/handling-webhook-events/#list-of-request-headers
+const (
+	eventSubMessageIDHeader        = "Twitch-Eventsub-Message-Id"
+	eventSubMessageTimestampHeader = "Twitch-Eventsub-Message-Timestamp"
+	eventSubMessageSignatureHeader = "Twitch-Eventsub-Message-Signature"
+	eventSubMessageTypeHeader      = "Twitch-Eventsub-Message-Type"
+	eventSubNotification           = "notification"
+)
+
+// Kinds of de-duplicated events
+const (
+	EventKindMessage    = "message"    // An EventSub delivery, keyed by its message ID
+	EventKindRedemption = "redemption" // A reward redemption, keyed by its redemption ID
+)
+
+// eventDedupTTL is how long handled events are remembered. Twitch stops retrying a delivery well before that.
+const eventDedupTTL = 24 * time.Hour
+
+// EventDeduplicator remembers handled EventSub messages and redemptions. Claims are kept in memory
+// and in the database, so retries are recognized after a restart too.
+type EventDeduplicator struct {
+	ttl         time.Duration
+	seen        map[string]time.Time // "<kind>:<id>" -> expiry
+	accepted    map[string]int64     // kind -> events processed
+	duplicates  map[string]int64     // kind -> duplicates dropped
+	mutex       sync.Mutex
+	cleanupOnce sync.Once
+}
+
+// EventDedupStats are the de-duplication counters since the server started
+type EventDedupStats struct {
+	Accepted   map[string]int64 `json:"accepted"`
+	Duplicates map[string]int64 `json:"duplicates"`
+}
+
+var globalEventDeduplicator = &EventDeduplicator{
+	ttl:        eventDedupTTL,
+	seen:       make(map[string]time.Time),
+	accepted:   make(map[string]int64),
+	duplicates: make(map[string]int64),
+}
+
+// GetEventDeduplicator returns the global event de-duplicator
+func GetEventDeduplicator() *EventDeduplicator {
+	return globalEventDeduplicator
+}
+
+// Claim marks an event as handled. Returns false if it was already handled and should be dropped.
+func (d *EventDeduplicator) Claim(kind, id string) bool {
+	key := kind + ":" + id
+
+	d.mutex.Lock()
+	if expiresAt, exists := d.seen[key]; exists && time.Now().Before(expiresAt) {
+		d.duplicates[kind]++
+		d.mutex.Unlock()
+		return false
+	}
+	d.mutex.Unlock()
+
+	claimed := true
+	if database := db.GetDB(); database != nil {
+		var err error
+		claimed, err = db.ClaimEvent(database, key, d.ttl)
+		if err != nil {
+			// Better to risk a duplicate than to drop the event
+			log.Printf("Error persisting event claim %s, keeping it in memory only: %v", key, err)
+			claimed = true
+		}
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.seen[key] = time.Now().Add(d.ttl)
+	if !claimed {
+		d.duplicates[kind]++
+		return false
+	}
+	d.accepted[kind]++
+	return true
+}
+
+// Release forgets a claim after the event failed to be handled, so a retry or the startup recovery
+// can handle it again
+func (d *EventDeduplicator) Release(kind, id string) {
+	key := kind + ":" + id
+
+	d.mutex.Lock()
+	delete(d.seen, key)
+	d.mutex.Unlock()
+
+	if database := db.GetDB(); database != nil {
+		if err := db.ReleaseEvent(database, key); err != nil {
+			log.Printf("Error releasing event claim %s: %v", key, err)
+		}
+	}
+}
+
+// Stats returns a snapshot of the de-duplication counters
+func (d *EventDeduplicator) Stats() EventDedupStats {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	stats := EventDedupStats{
+		Accepted:   make(map[string]int64),
+		Duplicates: make(map[string]int64),
+	}
+	for _, kind := range []string{EventKindMessage, EventKindRedemption} {
+		stats.Accepted[kind] = d.accepted[kind]
+		stats.Duplicates[kind] = d.duplicates[kind]
+	}
+	return stats
+}
+
+// Cleanup removes expired claims from memory and the database
+func (d *EventDeduplicator) Cleanup() {
+	d.mutex.Lock()
+	now := time.Now()
+	for key, expiresAt := range d.seen {
+		if !now.Before(expiresAt) {
+			delete(d.seen, key)
+		}
+	}
+	d.mutex.Unlock()
+
+	if database := db.GetDB(); database != nil {
+		if _, err := db.DeleteExpiredEvents(database); err != nil {
+			log.Printf("Error deleting expired event claims: %v", err)
+		}
+	}
+}
+
+// StartPeriodicCleanup starts a goroutine that periodically removes expired claims. Only the first call starts it.
+func (d *EventDeduplicator) StartPeriodicCleanup() {
+	d.cleanupOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(1 * time.Hour)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				d.Cleanup()
+			}
+		}()
+	})
+}
+
+// eventSubHandlers are the notification handlers by subscription type
+var eventSubHandlers = make(map[string]func(event json.RawMessage) error)
+
+// onEventSub registers the handler for notifications of a subscription type
+func onEventSub(subscriptionType string, handler func(event json.RawMessage) error) {
+	eventSubHandlers[subscriptionType] = handler
+}
+
+// eventSubPayload is the part of an EventSub notification body needed to dispatch it
+type eventSubPayload struct {
+	Subscription struct {
+		Type string `json:"type"`
+	} `json:"subscription"`
+	Event json.RawMessage `json:"event"`
+}
+
+// eventSubMaxAge is how old a delivery may be. Older ones are rejected as replays, since Twitch recommends
+// the same limit and message ID claims don't last forever.
+const eventSubMaxAge = 10 * time.Minute
+
+// handleEventSub handles signed EventSub notifications with the registered handlers and passes every other
+// request (verification challenges, revocations, bad signatures) on to the webhook client.
+// The webhook client remembers handled message IDs forever and answers before handling, so notifications
+// never reach it. Instead the message ID is claimed here and the handler runs before answering; if it fails,
+// the claim is released and Twitch gets an error, so it redelivers the notification.
+// Only deliveries with a valid signature are claimed, so forged requests can't block real ones.
+func handleEventSub(next http.HandlerFunc) http.HandlerFunc {
+	secret := os.Getenv("EVENTSUB_SECRET")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Printf("Error reading EventSub request body: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		messageID := r.Header.Get(eventSubMessageIDHeader)
+		if r.Header.Get(eventSubMessageTypeHeader) != eventSubNotification || messageID == "" ||
+			!validEventSubSignature(secret, r.Header, body) {
+			next(w, r)
+			return
+		}
+
+		sentAt, err := time.Parse(time.RFC3339, r.Header.Get(eventSubMessageTimestampHeader))
+		if err != nil || time.Since(sentAt) > eventSubMaxAge {
+			log.Printf("Rejecting EventSub delivery %s with stale or invalid timestamp %q", messageID, r.Header.Get(eventSubMessageTimestampHeader))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var payload eventSubPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			log.Printf("Error unmarshalling EventSub notification %s: %v", messageID, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		handler, exists := eventSubHandlers[payload.Subscription.Type]
+		if !exists {
+			log.Printf("No handler for EventSub notification %s of type %s", messageID, payload.Subscription.Type)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if !globalEventDeduplicator.Claim(EventKindMessage, messageID) {
+			log.Printf("Dropping duplicate EventSub delivery %s", messageID)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if err := runEventSubHandler(handler, payload.Event); err != nil {
+			log.Printf("Error handling EventSub notification %s of type %s: %v", messageID, payload.Subscription.Type, err)
+			globalEventDeduplicator.Release(EventKindMessage, messageID)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// runEventSubHandler runs a notification handler, turning a panic into an error
+func runEventSubHandler(handler func(event json.RawMessage) error, event json.RawMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(event)
+}
+
+// validEventSubSignature checks the HMAC signature Twitch puts on every EventSub request
+func validEventSubSignature(secret string, header http.Header, body []byte) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(header.Get(eventSubMessageIDHeader) + header.Get(eventSubMessageTimestampHeader)))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(header.Get(eventSubMessageSignatureHeader)))
+}
@@ -0,0 +1,148 @@
+package twitch
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"github.com/emcifuntik/twitch-spotify-request/internal/spotify"
+	spotifylib "github.com/zmb3/spotify/v2"
+)
+
+// ErrUnknownDevice is returned when a preferred device isn't among the streamer's Spotify devices
+var ErrUnknownDevice = errors.New("device not found among the streamer's Spotify devices")
+
+// deviceMissing returns since when Spotify has had no active device, and whether it has none right now
+func (rl *RewardListener) deviceMissing() (time.Time, bool) {
+	rl.pauseMutex.Lock()
+	defer rl.pauseMutex.Unlock()
+
+	return rl.deviceMissingSince, !rl.deviceMissingSince.IsZero()
+}
+
+// setDeviceActive records that Spotify has an active device. Returns true if it had none before.
+func (rl *RewardListener) setDeviceActive() bool {
+	rl.pauseMutex.Lock()
+	defer rl.pauseMutex.Unlock()
+
+	if rl.deviceMissingSince.IsZero() {
+		return false
+	}
+	log.Printf("Spotify device of streamer %d is active again after %s", rl.streamer.ID, time.Since(rl.deviceMissingSince).Round(time.Second))
+	rl.deviceMissingSince = time.Time{}
+	return true
+}
+
+// holdRequest puts a request that couldn't be handed to Spotify for lack of a device back at the head
+// of the queue. The redemption stays unfulfilled and the requester is told once that the song waits.
+func (rl *RewardListener) holdRequest(item *QueuedRequest) {
+	rl.pauseMutex.Lock()
+	if rl.deviceMissingSince.IsZero() {
+		rl.deviceMissingSince = time.Now()
+		log.Printf("No active Spotify device for streamer %d, holding requests", rl.streamer.ID)
+	}
+	rl.pauseMutex.Unlock()
+
+	notify := !item.HeldNotified
+	item.HeldNotified = true
+	rl.queue.PushFront(item)
+
+	if notify {
+		rl.sendMessage(fmt.Sprintf("@%s Spotify у стримера сейчас не активен, %s подождёт, пока плеер не включится",
+			item.UserName, spotify.SongItemToReadable(item.Track)))
+	}
+}
+
+// expireHeldRequests refunds requests that waited for an active Spotify device longer than the streamer allows
+func (rl *RewardListener) expireHeldRequests() {
+	missingSince, missing := rl.deviceMissing()
+	if !missing {
+		return
+	}
+
+	database := db.GetDB()
+	if database == nil {
+		return
+	}
+
+	timeout := time.Duration(db.GetHeldRequestTimeout(database, rl.streamer.ID)) * time.Second
+	if timeout <= 0 {
+		return
+	}
+
+	expired := 0
+	for _, queued := range rl.queue.Items() {
+		heldSince := missingSince
+		if queued.QueuedAt.After(heldSince) {
+			heldSince = queued.QueuedAt
+		}
+		if time.Since(heldSince) < timeout {
+			continue
+		}
+
+		item := rl.queue.Remove(queued.RequestID)
+		if item == nil {
+			continue
+		}
+		rl.refundQueuedRequest(item, "no_device")
+		rl.sendMessage(fmt.Sprintf("@%s Spotify так и не включился, заказ %s отменён",
+			item.UserName, spotify.SongItemToReadable(item.Track)))
+		expired++
+	}
+
+	if expired > 0 {
+		log.Printf("Refunded %d requests of streamer %d held without an active Spotify device", expired, rl.streamer.ID)
+		rl.UpdateRewardPauses()
+	}
+}
+
+// Devices returns the streamer's Spotify Connect devices and the ID of the preferred one, if any
+func (rl *RewardListener) Devices() ([]spotifylib.PlayerDevice, string, error) {
+	devices, err := rl.spotifyClient.GetDevices()
+	if err != nil {
+		return nil, "", err
+	}
+	return devices, rl.spotifyClient.DeviceID(), nil
+}
+
+// SetPreferredDevice makes the bot target a device, optionally moving playback to it.
+// An empty device ID makes the bot follow whichever device is active.
+func (rl *RewardListener) SetPreferredDevice(deviceID string, transfer bool) error {
+	database := db.GetDB()
+	if database == nil {
+		return fmt.Errorf("database not available")
+	}
+
+	if deviceID != "" {
+		devices, err := rl.spotifyClient.GetDevices()
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, device := range devices {
+			if string(device.ID) == deviceID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrUnknownDevice
+		}
+
+		if transfer {
+			if err := rl.spotifyClient.TransferPlayback(deviceID, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := db.SetConfig(database, rl.streamer.ID, db.ConfigKeyPreferredDevice, deviceID); err != nil {
+		return err
+	}
+	rl.spotifyClient.SetDeviceID(deviceID)
+
+	log.Printf("Preferred Spotify device of streamer %d set to %q", rl.streamer.ID, deviceID)
+	return nil
+}
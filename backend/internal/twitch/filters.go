@@ -0,0 +1,183 @@
+package twitch
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"github.com/emcifuntik/twitch-spotify-request/internal/spotify"
+	spotifylib "github.com/zmb3/spotify/v2"
+	"gorm.io/gorm"
+)
+
+// explicitAllowed checks if the requester may request explicit tracks under the streamer's filter
+func (rl *RewardListener) explicitAllowed(database *gorm.DB, user *Chatter) bool {
+	switch db.GetExplicitFilter(database, rl.streamer.ID) {
+	case db.ExplicitFilterBlock:
+		return false
+	case db.ExplicitFilterSubscribers:
+		// The broadcaster set the filter and can't subscribe to their own channel
+		return user.ID == rl.streamer.ChannelID || rl.isChatterSubscriber(user)
+	default:
+		return true
+	}
+}
+
+// cleanVersionOf returns a clean version of an explicit track, or nil if there is none or the fallback is disabled
+func (rl *RewardListener) cleanVersionOf(database *gorm.DB, track *spotifylib.FullTrack) *spotifylib.FullTrack {
+	if !db.IsExplicitFallbackEnabled(database, rl.streamer.ID) {
+		return nil
+	}
+
+	clean, err := rl.spotifyClient.FindCleanVersion(track)
+	if err != nil {
+		log.Printf("Error searching clean version of %s: %v", track.ID, err)
+		return nil
+	}
+	return clean
+}
+
+// checkTrackRules evaluates the streamer's track metadata rules.
+// Returns the rejection reason and chat message, or empty strings if the track passes.
+func (rl *RewardListener) checkTrackRules(rules db.TrackRules, track *spotifylib.FullTrack) (string, string) {
+	if rules.MinSongLength > 0 && int(track.Duration) < rules.MinSongLength*1000 {
+		return "too_short", fmt.Sprintf("трек слишком короткий (мин. %s)", formatDuration(rules.MinSongLength))
+	}
+
+	if rules.MinPopularity > 0 && int(track.Popularity) < rules.MinPopularity {
+		return "low_popularity", fmt.Sprintf("трек недостаточно популярен (популярность %d, нужно от %d)", track.Popularity, rules.MinPopularity)
+	}
+
+	if rules.MinReleaseYear > 0 || rules.MaxReleaseYear > 0 {
+		year := track.Album.ReleaseDateTime().Year()
+		if track.Album.ReleaseDate == "" {
+			log.Printf("Track %s has no release date, skipping release year rules", track.ID)
+		} else if rules.MinReleaseYear > 0 && year < rules.MinReleaseYear {
+			return "release_year", fmt.Sprintf("принимаются только треки не старше %d года (этот — %d)", rules.MinReleaseYear, year)
+		} else if rules.MaxReleaseYear > 0 && year > rules.MaxReleaseYear {
+			return "release_year", fmt.Sprintf("принимаются только треки до %d года включительно (этот — %d)", rules.MaxReleaseYear, year)
+		}
+	}
+
+	if len(rules.BlockedGenres) > 0 {
+		if genre := rl.blockedGenre(rules.BlockedGenres, track); genre != "" {
+			return "blocked_genre", fmt.Sprintf("жанр %s на этом канале не принимается", genre)
+		}
+	}
+
+	return "", ""
+}
+
+// blockedGenre returns the first artist genre of the track equal to a blocked genre, or an empty string
+func (rl *RewardListener) blockedGenre(blockedGenres []string, track *spotifylib.FullTrack) string {
+	var ids []spotifylib.ID
+	for _, artist := range track.Artists {
+		ids = append(ids, artist.ID)
+	}
+
+	artists, err := rl.spotifyClient.GetArtists(ids)
+	if err != nil {
+		log.Printf("Error getting artist genres for track %s: %v", track.ID, err)
+		return ""
+	}
+
+	for _, artist := range artists {
+		for _, blocked := range blockedGenres {
+			if genre := db.MatchGenre(blocked, artist.Genres); genre != "" {
+				return strings.ToLower(genre)
+			}
+		}
+	}
+	return ""
+}
+
+// blockTarget describes a track for the blocklist check, loading album labels and artist genres on demand
+func (rl *RewardListener) blockTarget(track *spotifylib.FullTrack) db.BlockTarget {
+	target := db.BlockTarget{
+		TrackID:   string(track.ID),
+		TrackName: track.Name,
+		AlbumID:   string(track.Album.ID),
+		AlbumName: track.Album.Name,
+	}
+
+	var artistIDs []spotifylib.ID
+	for _, artist := range track.Artists {
+		target.ArtistIDs = append(target.ArtistIDs, string(artist.ID))
+		target.ArtistNames = append(target.ArtistNames, artist.Name)
+		artistIDs = append(artistIDs, artist.ID)
+	}
+
+	target.LoadLabels = func() []string {
+		if track.Album.ID == "" {
+			return nil
+		}
+		album, err := rl.spotifyClient.GetAlbum(track.Album.ID)
+		if err != nil {
+			log.Printf("Error getting album of track %s for label blocks: %v", track.ID, err)
+			return nil
+		}
+		return spotify.AlbumLabels(album)
+	}
+
+	target.LoadGenres = func() []string {
+		artists, err := rl.spotifyClient.GetArtists(artistIDs)
+		if err != nil {
+			log.Printf("Error getting artist genres of track %s for genre blocks: %v", track.ID, err)
+			return nil
+		}
+		var genres []string
+		for _, artist := range artists {
+			genres = append(genres, artist.Genres...)
+		}
+		return genres
+	}
+
+	return target
+}
+
+// blockedMessage explains a block match in chat, with the block's reason if it has one
+func blockedMessage(match *db.BlockMatch) string {
+	var message string
+	switch db.BlockType(match.Block.Type) {
+	case db.BlockTypeAlbum:
+		message = fmt.Sprintf("альбом «%s» заблокирован", match.Block.Name)
+	case db.BlockTypeLabel:
+		message = fmt.Sprintf("треки лейбла «%s» заблокированы", match.Block.Name)
+	case db.BlockTypeGenre:
+		message = fmt.Sprintf("жанр %s на этом канале заблокирован", match.Value)
+	case db.BlockTypeKeyword, db.BlockTypeRegex:
+		field := "название трека"
+		switch match.Field {
+		case "artist":
+			field = "имя исполнителя"
+		case "album":
+			field = "название альбома"
+		}
+		message = fmt.Sprintf("%s «%s» попадает под запрет «%s»", field, match.Value, match.Block.Pattern)
+	default:
+		message = "этот трек или исполнитель заблокирован"
+	}
+
+	if match.Block.Reason != "" {
+		message += fmt.Sprintf(" (причина: %s)", match.Block.Reason)
+	}
+	return message
+}
+
+// deleteExpiredBlocks removes the streamer's blocks whose expiry has passed
+func (rl *RewardListener) deleteExpiredBlocks() {
+	database := db.GetDB()
+	if database == nil {
+		return
+	}
+
+	removed, err := db.DeleteExpiredBlocks(database, rl.streamer.ID)
+	if err != nil {
+		log.Printf("Error deleting expired blocks for streamer %d: %v", rl.streamer.ID, err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("Deleted %d expired blocks for streamer %d", removed, rl.streamer.ID)
+	}
+}
@@ -1,6 +1,7 @@
 package twitch
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"log"
@@ -8,9 +9,11 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"github.com/emcifuntik/twitch-spotify-request/internal/resolver"
 	"github.com/emcifuntik/twitch-spotify-request/internal/spotify"
 	"github.com/nicklaw5/helix/v2"
 	spotifylib "github.com/zmb3/spotify/v2"
@@ -34,8 +37,12 @@ func formatDuration(totalSeconds int) string {
 type RewardID int8
 
 const (
-	RewardIDRequestSong RewardID = 1
-	RewardIDSkipSong    RewardID = 2
+	RewardIDRequestSong     RewardID = 1
+	RewardIDSkipSong        RewardID = 2
+	RewardIDPriorityRequest RewardID = 3 // Song request that plays ahead of all other requests
+	RewardIDBanSong         RewardID = 4 // Bans the current song for the rest of the stream
+	RewardIDVolumeUp        RewardID = 5
+	RewardIDVolumeDown      RewardID = 6
 )
 
 // ChatCommand represents chat commands
@@ -49,8 +56,9 @@ const (
 	ChatCommandSongHelp    ChatCommand = "!songhelp"
 )
 
-// SongQueueData represents the current queue state using Spotify's native queue
+// SongQueueData represents the current queue state: bot-managed requests and Spotify's native queue
 type SongQueueData struct {
+	Requests     []QueuedRequest        `json:"requests"`
 	Queue        []spotifylib.FullTrack `json:"q"`
 	CurrentTrack *spotifylib.FullTrack  `json:"currentTrack,omitempty"`
 	Progress     int                    `json:"progress"`
@@ -68,14 +76,31 @@ type RewardListener struct {
 	lastQueue          *SongQueueData
 	streamerName       string
 	streamerNameUpdate int64
+	lastPlayingTrackID string
+	lastPushedTrackID  string // Track that was playing when a request was last handed to Spotify
+	queue              *RequestQueue
+	done               chan struct{} // Closed when the listener is invalidated
+	pauseMutex         sync.Mutex
+	streamOffline      bool              // Set once the stream is known to be offline
+	playbackIdle       bool              // Set while Spotify isn't playing
+	pausedRewards      map[RewardID]bool // Last pause state pushed to Twitch per reward
+	wantedPaused       map[RewardID]bool // Pause state each reward should have
+	pausing            map[RewardID]bool // Set while a pause state is being pushed to Twitch
+	deviceMissingSince time.Time         // When Spotify last reported no active device; zero while a device is active
+	missingScopes      map[string]bool   // Twitch scopes the streamer's token lacks, found when Helix rejects a call
+	scopeMutex         sync.Mutex
 }
 
 // Constants
 const (
 	QueueCalcDelay          = 30 * time.Second
 	AllowedBroadcasterTypes = "partner,affiliate"
+	ExternalLinkTimeout     = 10 * time.Second
 )
 
+// linkResolvers resolve YouTube, Apple Music and Deezer links in song requests
+var linkResolvers = resolver.DefaultResolvers()
+
 // Map with Twitch streamer IDs to their corresponding RewardListener instances.
 var rewardListeners = make(map[string]*RewardListener)
 
@@ -96,6 +121,7 @@ func InvalidateRewardListener(streamerID string) {
 	if listener, exists := rewardListeners[streamerID]; exists {
 		log.Printf("Invalidating listener for streamer %s", streamerID)
 		listener.client = nil               // Invalidate the client
+		close(listener.done)                // Stop background workers
 		delete(rewardListeners, streamerID) // Remove from map
 	} else {
 		log.Printf("No listener found for streamer %s to invalidate", streamerID)
@@ -132,11 +158,20 @@ func NewRewardListener(streamer *db.Streamer) *RewardListener {
 			return updateSpotifyTokens(streamer.ID, accessToken, refreshToken)
 		},
 	)
+	if database := db.GetDB(); database != nil {
+		spotifyClient.SetDeviceID(db.GetPreferredDevice(database, streamer.ID))
+	}
 
 	rl := &RewardListener{
 		streamer:      streamer,
 		client:        client,
 		spotifyClient: spotifyClient,
+		queue:         NewRequestQueue(),
+		done:          make(chan struct{}),
+		pausedRewards: make(map[RewardID]bool),
+		wantedPaused:  make(map[RewardID]bool),
+		pausing:       make(map[RewardID]bool),
+		missingScopes: make(map[string]bool),
 	}
 
 	// Load existing rewards
@@ -152,8 +187,24 @@ func NewRewardListener(streamer *db.Streamer) *RewardListener {
 	// Start periodic cleanup
 	rl.startPeriodicCleanup()
 
+	// Restore requests that were still queued before a restart
+	rl.restoreQueue()
+
+	// Fill the played ledger with what played while the listener wasn't running
+	go rl.syncRecentlyPlayed()
+
+	// Pause the rewards if the stream is offline
+	rl.refreshStreamStatus()
+
+	// Replay redemptions that arrived while the listener wasn't running
+	go rl.recoverRedemptions()
+
+	// Watch playback to feed queued requests to Spotify and track their lifecycle
+	rl.startPlaybackWatcher()
+
 	// Initialize cooldown manager cleanup (only once globally)
 	globalCooldownManager.StartPeriodicCleanup()
+	globalEventDeduplicator.StartPeriodicCleanup()
 
 	rewardListeners[streamer.ChannelID] = rl
 	AddStreamer(streamer.ChannelID)
@@ -261,136 +312,19 @@ func (rl *RewardListener) setupRewards() error {
 	// We'll skip this check for now and implement it later if needed
 	log.Printf("Setting up rewards for channel %s", rl.streamer.ChannelID)
 
-	// Set up song request reward
-	if !rl.hasReward(RewardIDRequestSong) {
-		if err := rl.setupSongRequestReward(); err != nil {
-			log.Printf("Error setting up song request reward: %v", err)
+	for _, rewardType := range rewardTypes {
+		if rl.hasReward(rewardType) {
+			log.Printf("Reward %s already exists for streamer %d", RewardTypeName(rewardType), rl.streamer.ID)
+			continue
 		}
-	} else {
-		log.Printf("Song request reward already exists for streamer %d", rl.streamer.ID)
-	}
-
-	// Set up skip song reward
-	if !rl.hasReward(RewardIDSkipSong) {
-		if err := rl.setupSkipSongReward(); err != nil {
-			log.Printf("Error setting up skip song reward: %v", err)
+		if !rl.rewardSettings(rewardType).Enabled {
+			continue
 		}
-	} else {
-		log.Printf("Skip song reward already exists for streamer %d", rl.streamer.ID)
-	}
-
-	return nil
-}
-
-// setupSongRequestReward creates the song request reward
-func (rl *RewardListener) setupSongRequestReward() error {
-	// Use a unique title to avoid conflicts
-	title := fmt.Sprintf("Request song (Bot %d)", rl.streamer.ID)
-
-	rewards, err := rl.client.GetCustomRewards(&helix.GetCustomRewardsParams{
-		BroadcasterID:         rl.streamer.ChannelID,
-		OnlyManageableRewards: true,
-	})
-
-	if err != nil {
-		return fmt.Errorf("failed to get existing rewards: %w", err)
-	}
-
-	if len(rewards.Data.ChannelCustomRewards) > 0 {
-		for _, reward := range rewards.Data.ChannelCustomRewards {
-			if strings.EqualFold(reward.Title, title) {
-				log.Printf("Song request reward already exists for streamer %d", rl.streamer.ID)
-				// Save the existing reward ID
-				if err := rl.saveReward(RewardIDRequestSong, reward.ID); err != nil {
-					log.Printf("Error saving existing song request reward to database: %v", err)
-				}
-				return nil // Reward already exists, no need to create again
-			}
-		}
-	}
-
-	response, err := rl.client.CreateCustomReward(&helix.ChannelCustomRewardsParams{
-		BroadcasterID:       rl.streamer.ChannelID,
-		Title:               title,
-		Cost:                300,
-		Prompt:              "Enter artist and song name to add request",
-		IsUserInputRequired: true,
-		BackgroundColor:     "#aaaa00",
-		IsEnabled:           true,
-	})
-
-	if err != nil {
-		return fmt.Errorf("failed to create song request reward: %w", err)
-	}
-
-	if len(response.Data.ChannelCustomRewards) == 0 {
-		return fmt.Errorf("no reward data returned")
-	}
-
-	rewardID := response.Data.ChannelCustomRewards[0].ID
-	log.Printf("Created song request reward with ID: %s", rewardID)
-
-	// Save to database
-	if err := rl.saveReward(RewardIDRequestSong, rewardID); err != nil {
-		log.Printf("Error saving song request reward to database: %v", err)
-	}
-
-	return nil
-}
-
-// setupSkipSongReward creates the skip song reward
-func (rl *RewardListener) setupSkipSongReward() error {
-	// Use a unique title to avoid conflicts
-	title := fmt.Sprintf("Skip song (Bot %d)", rl.streamer.ID)
-
-	rewards, err := rl.client.GetCustomRewards(&helix.GetCustomRewardsParams{
-		BroadcasterID:         rl.streamer.ChannelID,
-		OnlyManageableRewards: true,
-	})
-
-	if err != nil {
-		return fmt.Errorf("failed to get existing rewards: %w", err)
-	}
-
-	if len(rewards.Data.ChannelCustomRewards) > 0 {
-		for _, reward := range rewards.Data.ChannelCustomRewards {
-			if strings.EqualFold(reward.Title, title) {
-				log.Printf("Skip song reward already exists for streamer %d", rl.streamer.ID)
-				// Save the existing reward ID
-				if err := rl.saveReward(RewardIDSkipSong, reward.ID); err != nil {
-					log.Printf("Error saving existing skip song reward to database: %v", err)
-				}
-				return nil // Reward already exists, no need to create again
-			}
+		if err := rl.setupReward(rewardType); err != nil {
+			log.Printf("Error setting up %s reward: %v", RewardTypeName(rewardType), err)
 		}
 	}
 
-	response, err := rl.client.CreateCustomReward(&helix.ChannelCustomRewardsParams{
-		BroadcasterID:       rl.streamer.ChannelID,
-		Title:               title,
-		Cost:                1000,
-		Prompt:              "Skip current song",
-		IsUserInputRequired: false,
-		BackgroundColor:     "#00aaaa",
-		IsEnabled:           true,
-	})
-
-	if err != nil {
-		return fmt.Errorf("failed to create skip song reward: %w", err)
-	}
-
-	if len(response.Data.ChannelCustomRewards) == 0 {
-		return fmt.Errorf("no reward data returned")
-	}
-
-	rewardID := response.Data.ChannelCustomRewards[0].ID
-	log.Printf("Created skip song reward with ID: %s", rewardID)
-
-	// Save to database
-	if err := rl.saveReward(RewardIDSkipSong, rewardID); err != nil {
-		log.Printf("Error saving skip song reward to database: %v", err)
-	}
-
 	return nil
 }
 
@@ -408,10 +342,25 @@ func HandleRewardRedemption(streamerID string, redemptionID string, rewardID str
 	return rl.HandleRewardRedemption(redemptionID, rewardID, userID, userName, promptText)
 }
 
-// HandleRewardRedemption processes individual reward redemptions
-func (rl *RewardListener) HandleRewardRedemption(redemptionID string, rewardID string, userID string, userName string, promptText string) error {
+// HandleRewardRedemption processes individual reward redemptions. A redemption that fails to be handled
+// is released again, so the startup recovery can pick it up.
+func (rl *RewardListener) HandleRewardRedemption(redemptionID string, rewardID string, userID string, userName string, promptText string) (err error) {
 	log.Printf("Reward redeemed by %s (ID: %s) for reward ID: %s with input: %s", userName, userID, rewardID, promptText)
 
+	// The same redemption can arrive from a retried delivery and from startup recovery
+	if !globalEventDeduplicator.Claim(EventKindRedemption, redemptionID) {
+		log.Printf("Redemption %s was already handled, ignoring", redemptionID)
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic handling redemption %s: %v", redemptionID, r)
+		}
+		if err != nil {
+			globalEventDeduplicator.Release(EventKindRedemption, redemptionID)
+		}
+	}()
+
 	// Find the reward by Twitch ID
 	var rewardType RewardID = 0
 	var foundReward bool = false
@@ -441,87 +390,208 @@ func (rl *RewardListener) HandleRewardRedemption(redemptionID string, rewardID s
 	// Handle the reward based on type
 	switch rewardType {
 	case RewardIDRequestSong:
-		return rl.handleSongRequest(userName, promptText, redemptionID, rewardID)
+		return rl.handleSongRequest(&songRequest{
+			user:         &Chatter{ID: userID, Name: userName},
+			query:        promptText,
+			source:       db.RequestSourceReward,
+			redemptionID: redemptionID,
+			rewardID:     rewardID,
+		})
+	case RewardIDPriorityRequest:
+		return rl.handleSongRequest(&songRequest{
+			user:         &Chatter{ID: userID, Name: userName},
+			query:        promptText,
+			source:       db.RequestSourceReward,
+			redemptionID: redemptionID,
+			rewardID:     rewardID,
+			playNext:     true,
+		})
 	case RewardIDSkipSong:
 		return rl.handleSongSkip(userName, redemptionID, rewardID)
+	case RewardIDBanSong:
+		return rl.handleSongBan(userName, redemptionID, rewardID)
+	case RewardIDVolumeUp:
+		return rl.handleVolumeReward(userName, redemptionID, rewardID, 1)
+	case RewardIDVolumeDown:
+		return rl.handleVolumeReward(userName, redemptionID, rewardID, -1)
 	default:
 		log.Printf("Unknown reward type for reward ID %s, ignoring", rewardID)
 		return nil
 	}
 }
 
-// handleSongRequest processes song request rewards
-func (rl *RewardListener) handleSongRequest(userName, query, redemptionID, rewardID string) error {
+// songRequest describes a single song request travelling through the request pipeline
+type songRequest struct {
+	user         *Chatter
+	query        string
+	source       string // db.RequestSource*
+	redemptionID string // Empty for chat requests
+	rewardID     string
+	playNext     bool                  // Bought with the priority request reward
+	track        *spotifylib.FullTrack // Set once the query is resolved
+	tier         string                // Priority tier, set once the request is accepted
+	requestID    uint                  // Request log ID, set once recorded
+}
+
+// handleSongRequest processes song requests from rewards and chat commands
+func (rl *RewardListener) handleSongRequest(req *songRequest) error {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("Panic in handleSongRequest: %v", r)
 		}
 	}()
 
-	// Check if it's a Spotify URL
-	if spotify.IsSpotifyURL(query) {
-		return rl.handleSpotifyURL(userName, query, redemptionID, rewardID)
+	// Enforce the request switch, queue limits and per-viewer limits before spending any Spotify calls
+	if database := db.GetDB(); database != nil {
+		if reason, message := rl.checkRequestsOpen(database); reason != "" {
+			return rl.rejectRequest(req, reason, message)
+		}
+		if reason, message := rl.checkQuota(database, req.user); reason != "" {
+			return rl.rejectRequest(req, reason, message)
+		}
+	}
+
+	// Check if the request contains a Spotify link
+	if link := spotify.FindSpotifyLink(req.query); link != nil {
+		return rl.handleSpotifyLink(req, link)
+	}
+
+	// Check if the request contains a link from another platform
+	if linkResolver, link := resolver.FindLink(linkResolvers, req.query); linkResolver != nil {
+		return rl.handleExternalLink(req, linkResolver, link)
 	}
 
 	// Search for the track
-	return rl.handleSearchQuery(userName, query, redemptionID, rewardID)
+	return rl.handleSearchQuery(req)
 }
 
-// handleSpotifyURL processes Spotify URL requests
-func (rl *RewardListener) handleSpotifyURL(userName, url, redemptionID, rewardID string) error {
-	trackID := spotify.GetTrackIDFromURL(url)
-	if trackID == "" {
-		rl.sendMessage(fmt.Sprintf("@%s ничего не найдено в Spotify", userName))
-		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+// handleSpotifyLink processes requests made with a Spotify link or URI
+func (rl *RewardListener) handleSpotifyLink(req *songRequest, link *spotify.Link) error {
+	if link.Type == spotify.LinkTypeShort {
+		resolved, err := spotify.ResolveShortLink(link.URL)
+		if err != nil {
+			log.Printf("Error resolving short link %s: %v", link.URL, err)
+			return rl.rejectRequest(req, "not_found", "не удалось открыть ссылку Spotify")
+		}
+		link = resolved
+	}
+
+	if link.Type != spotify.LinkTypeTrack {
+		return rl.rejectRequest(req, "not_a_track", fmt.Sprintf("заказать можно только отдельный трек, %s не подходят", linkTypeName(link.Type)))
 	}
 
-	track, err := rl.spotifyClient.GetTrackByID(trackID)
+	track, err := rl.spotifyClient.GetTrackByID(link.ID)
 	if err != nil || track.URI == "" {
-		rl.sendMessage(fmt.Sprintf("@%s ничего не найдено в Spotify", userName))
-		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+		return rl.rejectRequest(req, "not_found", "ничего не найдено в Spotify")
+	}
+
+	return rl.enqueueTrack(req, track)
+}
+
+// handleExternalLink resolves a link from another platform and matches it to a Spotify track
+func (rl *RewardListener) handleExternalLink(req *songRequest, linkResolver resolver.Resolver, link string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), ExternalLinkTimeout)
+	defer cancel()
+
+	info, err := linkResolver.Resolve(ctx, link)
+	if err != nil {
+		log.Printf("Error resolving %s link %s: %v", linkResolver.Name(), link, err)
+		return rl.rejectRequest(req, "not_found", "не удалось распознать трек по ссылке")
+	}
+
+	log.Printf("Resolved %s link %s to %q (ISRC %s)", linkResolver.Name(), link, info.Query(), info.ISRC)
+
+	// An ISRC identifies the exact recording, so prefer it over a text search
+	if info.ISRC != "" {
+		track, err := rl.spotifyClient.SearchTrackByISRC(info.ISRC)
+		if err != nil {
+			log.Printf("Error searching Spotify by ISRC %s: %v", info.ISRC, err)
+		} else if track != nil {
+			return rl.enqueueTrack(req, track)
+		}
+	}
+
+	track, err := rl.spotifyClient.SearchTrack(info.Query())
+	if err != nil {
+		log.Printf("Error searching tracks: %v", err)
+		return rl.rejectRequest(req, "not_found", "ничего не найдено в Spotify")
+	}
+
+	if track == nil {
+		return rl.rejectRequest(req, "not_found", fmt.Sprintf("%s не найден в Spotify", info.Query()))
 	}
 
-	return rl.enqueueTrack(userName, track, redemptionID, rewardID)
+	return rl.enqueueTrack(req, track)
+}
+
+// linkTypeName returns the chat name of a non-track Spotify link type
+func linkTypeName(linkType spotify.LinkType) string {
+	switch linkType {
+	case spotify.LinkTypeAlbum:
+		return "альбомы"
+	case spotify.LinkTypePlaylist:
+		return "плейлисты"
+	case spotify.LinkTypeArtist:
+		return "ссылки на исполнителей"
+	case spotify.LinkTypeEpisode, spotify.LinkTypeShow:
+		return "подкасты"
+	default:
+		return "такие ссылки"
+	}
 }
 
 // handleSearchQuery processes search query requests
-func (rl *RewardListener) handleSearchQuery(userName, query, redemptionID, rewardID string) error {
-	searchResult, err := rl.spotifyClient.SearchTracks(query)
+func (rl *RewardListener) handleSearchQuery(req *songRequest) error {
+	track, err := rl.spotifyClient.SearchTrack(req.query)
 	if err != nil {
 		log.Printf("Error searching tracks: %v", err)
-		rl.sendMessage(fmt.Sprintf("@%s ничего не найдено в Spotify", userName))
-		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+		return rl.rejectRequest(req, "not_found", "ничего не найдено в Spotify")
 	}
 
-	if len(searchResult.Tracks.Tracks) == 0 {
-		rl.sendMessage(fmt.Sprintf("@%s ничего не найдено в Spotify", userName))
-		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+	if track == nil {
+		return rl.rejectRequest(req, "not_found", "ничего не найдено в Spotify")
 	}
 
-	track := &searchResult.Tracks.Tracks[0]
-	return rl.enqueueTrack(userName, track, redemptionID, rewardID)
+	return rl.enqueueTrack(req, track)
 }
 
 // enqueueTrack adds a track to the Spotify queue with enhanced validation
-func (rl *RewardListener) enqueueTrack(userName string, track *spotifylib.FullTrack, redemptionID, rewardID string) error {
+func (rl *RewardListener) enqueueTrack(req *songRequest, track *spotifylib.FullTrack) error {
+	req.track = track
+
 	database := db.GetDB()
 	if database == nil {
 		log.Printf("Database not available for track validation")
-		rl.sendMessage(fmt.Sprintf("@%s произошла ошибка при обработке запроса", userName))
-		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+		return rl.rejectRequest(req, "internal_error", "произошла ошибка при обработке запроса")
 	}
 
-	// Get artist IDs and track ID for blocking check
-	var artistIDs []string
-	for _, artist := range track.Artists {
-		artistIDs = append(artistIDs, string(artist.ID))
+	// Apply the explicit content filter, swapping in a clean version when there is one
+	if track.Explicit && !rl.explicitAllowed(database, req.user) {
+		clean := rl.cleanVersionOf(database, track)
+		if clean == nil {
+			return rl.rejectRequest(req, "explicit", "треки с ненормативной лексикой на этом канале не принимаются")
+		}
+		log.Printf("Replaced explicit track %s with clean version %s", track.ID, clean.ID)
+		track = clean
+		req.track = clean
 	}
-	trackID := string(track.ID)
 
 	// Check if track/artist is blocked
-	if db.IsBlocked(database, rl.streamer.ID, artistIDs, trackID) {
-		rl.sendMessage(fmt.Sprintf("@%s этот трек или исполнитель заблокирован", userName))
-		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+	match, err := db.CheckBlocked(database, rl.streamer.ID, rl.blockTarget(track))
+	if err != nil {
+		log.Printf("Error checking blocks: %v", err)
+		return rl.rejectRequest(req, "internal_error", "произошла ошибка при обработке запроса")
+	}
+	if match != nil {
+		log.Printf("Track %s matched block %d (%s %q) on %s", track.ID, match.Block.ID, match.Block.Type, match.Block.Name, match.Field)
+		return rl.rejectRequest(req, "blocked", blockedMessage(match))
+	}
+
+	// In allowlist mode only tracks from the streamer's playlists are accepted
+	if db.IsPlaylistAllowlistEnabled(database, rl.streamer.ID) {
+		if reason, message := rl.checkPlaylistAllowlist(database, track); reason != "" {
+			return rl.rejectRequest(req, reason, message)
+		}
 	}
 
 	// Check max song length
@@ -529,49 +599,123 @@ func (rl *RewardListener) enqueueTrack(userName string, track *spotifylib.FullTr
 	if int(track.Duration) > maxLength*1000 { // Duration is in milliseconds
 		minutes := maxLength / 60
 		seconds := maxLength % 60
-		rl.sendMessage(fmt.Sprintf("@%s трек слишком длинный (макс. %d:%02d)", userName, minutes, seconds))
-		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+		return rl.rejectRequest(req, "too_long", fmt.Sprintf("трек слишком длинный (макс. %d:%02d)", minutes, seconds))
 	}
 
-	// Check cooldown for the same song
+	// Check the streamer's metadata rules
+	if reason, message := rl.checkTrackRules(db.GetTrackRules(database, rl.streamer.ID), track); reason != "" {
+		return rl.rejectRequest(req, reason, message)
+	}
+
+	// Check the track, artist and album cooldowns
 	cooldownManager := GetCooldownManager()
-	cooldownSeconds := db.GetCooldownSameSong(database, rl.streamer.ID)
+	cooldownSubjects := trackCooldownSubjects(track)
+	if block := cooldownManager.GetRemainingCooldown(rl.streamer.ID, cooldownSubjects); block != nil {
+		return rl.rejectRequest(req, "cooldown_"+block.Kind, cooldownMessage(block))
+	}
 
-	if cooldownManager.IsOnCooldown(rl.streamer.ChannelID, string(track.URI), cooldownSeconds) {
-		remaining := cooldownManager.GetRemainingCooldown(rl.streamer.ChannelID, string(track.URI), cooldownSeconds)
-		timeStr := formatDuration(remaining)
-		rl.sendMessage(fmt.Sprintf("@%s этот трек недавно играл, повторить можно через %s", userName, timeStr))
-		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+	// Check if the track recently played on the channel
+	if reason, message := rl.checkDuplicate(database, string(track.ID)); reason != "" {
+		return rl.rejectRequest(req, reason, message)
 	}
 
-	// Check for duplicates (existing logic)
-	if spotify.GlobalDuplicateStore.Exists(string(track.URI)) {
-		rl.sendMessage(fmt.Sprintf("@%s этот трек уже играл за последний час", userName))
-		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+	// Check if the track is already waiting in the request queue
+	if rl.queue.Contains(track.ID) {
+		return rl.rejectRequest(req, "already_queued", "этот трек уже есть в очереди")
 	}
 
 	songName := spotify.SongItemToReadable(track)
 
-	// Add to Spotify queue
-	if err := rl.spotifyClient.EnqueueTrack(track.URI); err != nil {
-		log.Printf("Error enqueueing track: %v", err)
-		rl.sendMessage(fmt.Sprintf("@%s произошла ошибка при добавлении трека", userName))
-		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+	tiers := db.GetPriorityTiers(database, rl.streamer.ID)
+	if req.playNext {
+		req.tier = db.PriorityTierPlayNext
+	} else {
+		req.tier = rl.requestTier(req.user, tiers, db.GetPriorityBitsMin(database, rl.streamer.ID))
+	}
+
+	// Hold the request in the bot-managed queue; the redemption stays unfulfilled
+	// until the track is handed to Spotify, so it can still be refunded
+	_, held := rl.deviceMissing()
+	rl.recordRequest(req, db.RequestStatusQueued, "")
+	position := rl.queue.Push(&QueuedRequest{
+		RequestID:    req.requestID,
+		Track:        track,
+		UserID:       req.user.ID,
+		UserName:     req.user.Name,
+		RedemptionID: req.redemptionID,
+		RewardID:     req.rewardID,
+		Tier:         req.tier,
+		Rank:         tierRank(tiers, req.tier),
+		QueuedAt:     time.Now(),
+		HeldNotified: held,
+	})
+
+	// Pause the request rewards if this request filled the queue
+	rl.UpdateRewardPauses()
+
+	// Start the track, artist, album and viewer cooldowns
+	cooldownManager.AddCooldown(rl.streamer.ID, append(cooldownSubjects, userCooldownSubject(req.user)))
+
+	message := fmt.Sprintf("@%s %s добавлена в очередь (позиция %d)", req.user.Name, songName, position)
+	if label := tierLabel(req.tier); label != "" {
+		message = fmt.Sprintf("@%s %s добавлена в очередь (позиция %d, приоритет: %s)", req.user.Name, songName, position, label)
 	}
+	if held {
+		message += "; Spotify у стримера сейчас не активен, трек подождёт, пока плеер не включится"
+	}
+	rl.sendMessage(message)
+	return nil
+}
 
-	// Add to duplicate store
-	spotify.GlobalDuplicateStore.Add(string(track.URI))
+// rejectRequest records the rejection, notifies the requester and refunds the redemption, if any
+func (rl *RewardListener) rejectRequest(req *songRequest, reason, message string) error {
+	rl.recordRequest(req, db.RequestStatusRejected, reason)
+	rl.sendMessage(fmt.Sprintf("@%s %s", req.user.Name, message))
+	if req.redemptionID == "" {
+		return nil
+	}
+	return rl.updateRedemptionStatus(req.redemptionID, req.rewardID, "CANCELED")
+}
 
-	// Add to cooldown manager
-	cooldownManager.AddCooldown(rl.streamer.ChannelID, string(track.URI))
+// recordRequest persists a song request and its outcome in the request log
+func (rl *RewardListener) recordRequest(req *songRequest, status, reason string) {
+	database := db.GetDB()
+	if database == nil {
+		log.Printf("Database not available for recording request")
+		return
+	}
 
-	rl.sendMessage(fmt.Sprintf("@%s %s добавлена в очередь", userName, songName))
-	return rl.updateRedemptionStatus(redemptionID, rewardID, "FULFILLED")
+	user, err := db.UpsertUser(database, req.user.ID, req.user.Name)
+	if err != nil {
+		log.Printf("Error saving requester %s: %v", req.user.Name, err)
+		return
+	}
+
+	request := db.Request{
+		StreamerID:   rl.streamer.ID,
+		UserID:       user.ID,
+		SearchPrompt: req.query,
+		Source:       req.source,
+		Status:       status,
+		Reason:       reason,
+		RedemptionID: req.redemptionID,
+		Tier:         req.tier,
+	}
+	if req.track != nil {
+		request.TrackID = string(req.track.ID)
+		request.TrackName = spotify.SongItemToReadable(req.track)
+	}
+
+	if err := db.CreateRequest(database, &request); err != nil {
+		log.Printf("Error recording request from %s: %v", req.user.Name, err)
+		return
+	}
+	req.requestID = request.ID
 }
 
 // handleSongSkip processes song skip rewards
 func (rl *RewardListener) handleSongSkip(userName, redemptionID, rewardID string) error {
-	if err := rl.spotifyClient.NextTrack(); err != nil {
+	if err := rl.skipCurrentTrack(fmt.Sprintf("skipped by %s via reward", userName)); err != nil {
 		log.Printf("Error skipping track: %v", err)
 		rl.sendMessage(fmt.Sprintf("@%s произошла ошибка при пропуске трека", userName))
 		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
@@ -658,8 +802,20 @@ func (rl *RewardListener) getChannelName() string {
 	return rl.streamerName
 }
 
-// GetQueueData calculates and returns current queue data using Spotify's native queue
+// GetQueueData returns the bot-managed request queue along with Spotify's native queue
 func (rl *RewardListener) GetQueueData() (*SongQueueData, error) {
+	spotifyData, err := rl.getSpotifyQueueData()
+	if err != nil {
+		return nil, err
+	}
+
+	data := *spotifyData
+	data.Requests = rl.queue.Items()
+	return &data, nil
+}
+
+// getSpotifyQueueData calculates and returns current queue data using Spotify's native queue
+func (rl *RewardListener) getSpotifyQueueData() (*SongQueueData, error) {
 	now := time.Now().Unix()
 	if now-rl.lastQueueCalcTime < int64(QueueCalcDelay.Seconds()) && rl.lastQueue != nil {
 		return rl.lastQueue, nil
@@ -722,15 +878,26 @@ func FixRewardsForChannel(channelID string) error {
 }
 
 // HandleChatCommand processes chat commands
-func (rl *RewardListener) HandleChatCommand(userName, command, args string) {
+func (rl *RewardListener) HandleChatCommand(user *Chatter, command, args string) {
+	userName := user.Name
 	log.Printf("Processing chat command: %s from user: %s with args: %s", command, userName, args)
 
+	// Streamer-configured commands take precedence over the built-in ones
+	if rl.handleConfiguredCommand(user, command, args) {
+		return
+	}
+
 	switch ChatCommand(command) {
 	case ChatCommandSongHelp:
 		log.Printf("Handling song help command for user: %s", userName)
 		rl.handleSongHelp(userName)
 	case ChatCommandSongVolume:
 		log.Printf("Handling volume command for user: %s with args: %s", userName, args)
+		if !rl.hasPermission(user, RoleModerator) {
+			log.Printf("Volume command denied: user %s is not mod or broadcaster", userName)
+			rl.sendMessage(fmt.Sprintf("@%s Only moderators and the broadcaster can change volume", userName))
+			return
+		}
 		rl.handleVolumeCommand(userName, args)
 	case ChatCommandSongsRecent:
 		log.Printf("Handling recent songs command for user: %s", userName)
@@ -748,7 +915,32 @@ func (rl *RewardListener) HandleChatCommand(userName, command, args string) {
 
 // handleSongHelp shows available commands
 func (rl *RewardListener) handleSongHelp(userName string) {
-	rl.sendMessage(fmt.Sprintf("@%s Available commands: !sc - current song; !sq - view queue; !sr - recent songs; !volume <0-100> - change volume (mods/broadcaster only)", userName))
+	help := "!sc - current song; !sq - view queue; !sr - recent songs; !volume <0-100> - change volume (mods/broadcaster only)"
+
+	database := db.GetDB()
+	if database != nil {
+		commands, err := db.GetStreamerCommands(database, rl.streamer.ID)
+		if err != nil {
+			log.Printf("Error getting commands for help: %v", err)
+		}
+
+		var configured []string
+		for _, cmd := range commands {
+			if cmd.IsEnabled && !IsReservedCommandName(cmd.Name, cmd.Type) {
+				configured = append(configured, fmt.Sprintf("!%s - %s", cmd.Name, cmd.Type))
+			}
+		}
+		if len(configured) > 0 {
+			help = strings.Join(configured, "; ") + "; !sc - current song; !sr - recent songs"
+		}
+	}
+
+	rl.sendMessage(fmt.Sprintf("@%s Available commands: %s", userName, help))
+}
+
+// SetUseCommands updates whether the listener accepts song requests from chat
+func (rl *RewardListener) SetUseCommands(useCommands bool) {
+	rl.streamer.UseCommands = useCommands
 }
 
 // handleVolumeCommand changes the volume
@@ -768,16 +960,6 @@ func (rl *RewardListener) handleVolumeCommand(userName, args string) {
 		return
 	}
 
-	log.Printf("Checking permissions for user %s", userName)
-	// Check if user is mod or broadcaster
-	if !rl.isUserModOrBroadcaster(userName) {
-		log.Printf("Volume command denied: user %s is not mod or broadcaster", userName)
-		rl.sendMessage(fmt.Sprintf("@%s Only moderators and the broadcaster can change volume", userName))
-		return
-	}
-
-	log.Printf("Permission check passed for user %s", userName)
-
 	// Clamp volume between 0 and 100
 	originalVolume := volume
 	if volume < 0 {
@@ -802,62 +984,6 @@ func (rl *RewardListener) handleVolumeCommand(userName, args string) {
 	rl.sendMessage(fmt.Sprintf("@%s Volume set to %d%%", userName, volume))
 }
 
-// isUserModOrBroadcaster checks if a user is a moderator or the broadcaster
-func (rl *RewardListener) isUserModOrBroadcaster(userName string) bool {
-	log.Printf("Checking if user %s is mod or broadcaster", userName)
-
-	channelName := rl.getChannelName()
-	log.Printf("Channel name: %s", channelName)
-
-	if channelName == "" {
-		log.Printf("Channel name is empty, denying permission")
-		return false
-	}
-
-	// Check if user is the broadcaster
-	if strings.EqualFold(userName, channelName) {
-		log.Printf("User %s is the broadcaster (channel: %s)", userName, channelName)
-		return true
-	}
-
-	log.Printf("User %s is not the broadcaster, checking bot moderators", userName)
-
-	// Check if user is a bot moderator
-	database := db.GetDB()
-	if database != nil {
-		if db.IsBotModeratorByName(database, rl.streamer.ID, userName) {
-			log.Printf("User %s is a bot moderator", userName)
-			return true
-		}
-	}
-
-	log.Printf("User %s is not a bot moderator, checking Twitch moderators", userName)
-
-	// Get moderators list from Twitch
-	mods, err := rl.client.GetModerators(&helix.GetModeratorsParams{
-		BroadcasterID: rl.streamer.ChannelID,
-	})
-
-	if err != nil {
-		log.Printf("Error getting moderators for channel %s (ID: %s): %v", channelName, rl.streamer.ChannelID, err)
-		return false
-	}
-
-	log.Printf("Found %d Twitch moderators for channel %s", len(mods.Data.Moderators), channelName)
-
-	// Check if user is in Twitch moderators list
-	for _, mod := range mods.Data.Moderators {
-		log.Printf("Checking moderator: %s vs user: %s", mod.UserName, userName)
-		if strings.EqualFold(mod.UserName, userName) {
-			log.Printf("User %s found in Twitch moderators list", userName)
-			return true
-		}
-	}
-
-	log.Printf("User %s not found in moderators list", userName)
-	return false
-}
-
 // handleRecentSongs shows recently played tracks
 func (rl *RewardListener) handleRecentSongs(userName string) {
 	recentTracks, err := rl.spotifyClient.GetRecentlyPlayed(5)
@@ -887,19 +1013,18 @@ func (rl *RewardListener) handleCurrentSong(userName string) {
 	rl.sendMessage(fmt.Sprintf("@%s Current track: %s", userName, songName))
 }
 
-// handleSongQueue shows the current queue
+// handleSongQueue shows the current request queue
 func (rl *RewardListener) handleSongQueue(userName string) {
-	queueData, err := rl.GetQueueData()
-	if err != nil {
-		log.Printf("Error getting queue data: %v", err)
-		rl.sendMessage(fmt.Sprintf("@%s Error getting queue", userName))
+	requests := rl.queue.Items()
+	if len(requests) == 0 {
+		rl.sendMessage(fmt.Sprintf("@%s The request queue is empty", userName))
 		return
 	}
 
 	var prettyQueue []string
-	for _, item := range queueData.Queue {
-		songName := spotify.SongItemToReadable(&item)
-		prettyQueue = append(prettyQueue, songName)
+	for _, item := range requests {
+		songName := spotify.SongItemToReadable(item.Track)
+		prettyQueue = append(prettyQueue, fmt.Sprintf("%s (%s)", songName, item.UserName))
 	}
 
 	if len(prettyQueue) > 5 {
@@ -913,7 +1038,7 @@ func (rl *RewardListener) handleSongQueue(userName string) {
 // isValidRewardType checks if a reward type is valid and expected
 func isValidRewardType(rewardType RewardID) bool {
 	switch rewardType {
-	case RewardIDRequestSong, RewardIDSkipSong:
+	case RewardIDRequestSong, RewardIDSkipSong, RewardIDPriorityRequest, RewardIDBanSong, RewardIDVolumeUp, RewardIDVolumeDown:
 		return true
 	default:
 		return false
@@ -930,14 +1055,23 @@ func (rl *RewardListener) hasReward(rewardType RewardID) bool {
 	return false
 }
 
+// rewardTwitchID returns the Twitch ID of a reward type, or an empty string if it isn't set up
+func (rl *RewardListener) rewardTwitchID(rewardType RewardID) string {
+	for _, reward := range rl.rewards {
+		if RewardID(reward.InternalID) == rewardType {
+			return reward.TwitchID
+		}
+	}
+	return ""
+}
+
 // CheckRewardsConfigured checks if all required rewards are properly configured
 func (rl *RewardListener) CheckRewardsConfigured() bool {
-	// Check if both required rewards exist
-	hasRequestReward := rl.hasReward(RewardIDRequestSong)
-	hasSkipReward := rl.hasReward(RewardIDSkipSong)
-
-	if !hasRequestReward || !hasSkipReward {
-		return false
+	// Check if all enabled bot rewards exist
+	for _, rewardType := range rewardTypes {
+		if rl.rewardSettings(rewardType).Enabled && !rl.hasReward(rewardType) {
+			return false
+		}
 	}
 
 	// Additionally, verify that the rewards still exist on Twitch
@@ -1036,7 +1170,7 @@ func (rl *RewardListener) cleanupExistingRewards() error {
 }
 
 // HandleChatMessage handles chat messages from EventSub
-func HandleChatMessage(broadcasterUserID string, chatterUserID string, chatterUserName string, messageText string) error {
+func HandleChatMessage(broadcasterUserID string, chatterUserID string, chatterUserName string, badges []Badge, bits int, messageText string) error {
 	log.Printf("Handling chat message from %s (ID: %s) in channel %s: %s", chatterUserName, chatterUserID, broadcasterUserID, messageText)
 
 	rl, exists := rewardListeners[broadcasterUserID]
@@ -1062,20 +1196,43 @@ func HandleChatMessage(broadcasterUserID string, chatterUserID string, chatterUs
 	}
 
 	log.Printf("Processing chat command: %s with args: %s from user: %s", command, args, chatterUserName)
-	rl.HandleChatCommand(chatterUserName, command, args)
+	rl.HandleChatCommand(&Chatter{ID: chatterUserID, Name: chatterUserName, Badges: badges, Bits: bits}, command, args)
 	return nil
 }
 
+// stripCheermotes rebuilds a cheer message without its cheermotes. Twitch marks the cheermotes as message
+// fragments, so words that only look like one, such as "Blink182", are kept.
+func stripCheermotes(fragments []Fragment) string {
+	var builder strings.Builder
+	for _, fragment := range fragments {
+		if fragment.Type != "cheermote" {
+			builder.WriteString(fragment.Text)
+		}
+	}
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
 // startPeriodicCleanup starts background cleanup tasks
 func (rl *RewardListener) startPeriodicCleanup() {
 	ticker := time.NewTicker(10 * time.Minute) // Run cleanup every 10 minutes
 
 	go func() {
 		defer ticker.Stop()
-		for range ticker.C {
-			// Clean up duplicate store
-			spotify.GlobalDuplicateStore.Cleanup()
-			log.Printf("Performed periodic cleanup for streamer %d", rl.streamer.ID)
+		for {
+			select {
+			case <-rl.done:
+				log.Printf("Stopped periodic cleanup for streamer %d", rl.streamer.ID)
+				return
+			case <-ticker.C:
+				rl.syncRecentlyPlayed()
+				rl.cleanupPlayedTracks()
+				spotify.GlobalArtistCache.Cleanup()
+				spotify.GlobalAlbumCache.Cleanup()
+				rl.refreshAllowedPlaylists()
+				rl.deleteExpiredBlocks()
+				rl.refreshStreamStatus()
+				log.Printf("Performed periodic cleanup for streamer %d", rl.streamer.ID)
+			}
 		}
 	}()
 }
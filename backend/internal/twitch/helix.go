@@ -0,0 +1,67 @@
+package twitch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// helixBaseURL is the Helix API root used for the calls the helix client doesn't cover
+var helixBaseURL = "https://api.twitch.tv/helix"
+
+// errListenerInvalidated is returned by Helix calls of a listener whose client was invalidated
+var errListenerInvalidated = errors.New("reward listener was invalidated")
+
+// rawHelixClient sends the raw Helix requests
+var rawHelixClient = &http.Client{Timeout: 10 * time.Second}
+
+// helixRequest sends a raw Helix request with the streamer's token.
+// The body and result are JSON encoded and decoded when not nil.
+func (rl *RewardListener) helixRequest(method, path string, query url.Values, body, result interface{}) error {
+	client := rl.client
+	if client == nil {
+		return errListenerInvalidated
+	}
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, helixBaseURL+path+"?"+query.Encode(), reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Client-Id", os.Getenv("TWITCH_CLIENT_ID"))
+	req.Header.Set("Authorization", "Bearer "+client.GetUserAccessToken())
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := rawHelixClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("helix %s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("helix %s %s failed: status %d", method, path, resp.StatusCode)
+	}
+
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("failed to parse helix %s response: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -30,28 +30,59 @@ func InitTwitchWhClient() (*twitchwh.Client, error) {
 func InitTwitchEventSub() (func(w http.ResponseWriter, r *http.Request), error) {
 	TwitchwhClient.RemoveSubscriptionByType("channel.channel_points_custom_reward_redemption.add", twitchwh.Condition{})
 	TwitchwhClient.RemoveSubscriptionByType("channel.chat.message", twitchwh.Condition{})
+	TwitchwhClient.RemoveSubscriptionByType("stream.online", twitchwh.Condition{})
+	TwitchwhClient.RemoveSubscriptionByType("stream.offline", twitchwh.Condition{})
 
 	// Handle reward redemption events
-	TwitchwhClient.On("channel.channel_points_custom_reward_redemption.add", func(event json.RawMessage) {
+	onEventSub("channel.channel_points_custom_reward_redemption.add", func(event json.RawMessage) error {
 		var data RewardRedemptionEvent
 		if err := json.Unmarshal(event, &data); err != nil {
 			log.Printf("Error unmarshalling EventSub reward event: %v", err)
-			return
+			return nil // A redelivery won't parse either
 		}
-		HandleRewardRedemption(data.BroadcasterUserID, data.ID, data.Reward.ID, data.UserID, data.UserName, data.UserInput)
+		return HandleRewardRedemption(data.BroadcasterUserID, data.ID, data.Reward.ID, data.UserID, data.UserName, data.UserInput)
 	})
 
 	// Handle chat message events
-	TwitchwhClient.On("channel.chat.message", func(event json.RawMessage) {
+	onEventSub("channel.chat.message", func(event json.RawMessage) error {
 		var data ChatMessageEvent
 		if err := json.Unmarshal(event, &data); err != nil {
 			log.Printf("Error unmarshalling EventSub chat event: %v", err)
-			return
+			return nil
 		}
-		HandleChatMessage(data.BroadcasterUserID, data.ChatterUserID, data.ChatterUserName, data.Message.Text)
+		bits := 0
+		text := data.Message.Text
+		if data.Cheer != nil {
+			bits = data.Cheer.Bits
+			// Cheermotes are part of the message text and would end up in the search query
+			text = stripCheermotes(data.Message.Fragments)
+		}
+		HandleChatMessage(data.BroadcasterUserID, data.ChatterUserID, data.ChatterUserName, data.Badges, bits, text)
+		return nil
+	})
+
+	// Handle stream status events, which pause and resume the rewards
+	onEventSub("stream.online", func(event json.RawMessage) error {
+		var data StreamStatusEvent
+		if err := json.Unmarshal(event, &data); err != nil {
+			log.Printf("Error unmarshalling EventSub stream.online event: %v", err)
+			return nil
+		}
+		HandleStreamOnline(data.BroadcasterUserID)
+		return nil
+	})
+
+	onEventSub("stream.offline", func(event json.RawMessage) error {
+		var data StreamStatusEvent
+		if err := json.Unmarshal(event, &data); err != nil {
+			log.Printf("Error unmarshalling EventSub stream.offline event: %v", err)
+			return nil
+		}
+		HandleStreamOffline(data.BroadcasterUserID)
+		return nil
 	})
 
-	return TwitchwhClient.Handler, nil
+	return handleEventSub(TwitchwhClient.Handler), nil
 }
 
 func AddStreamer(streamerId string) error {
@@ -76,6 +107,17 @@ func AddStreamer(streamerId string) error {
 		return err
 	}
 
+	// Subscribe to stream status events
+	for _, eventType := range []string{"stream.online", "stream.offline"} {
+		err = TwitchwhClient.AddSubscription(eventType, "1", twitchwh.Condition{
+			BroadcasterUserID: streamerId,
+		})
+		if err != nil {
+			log.Printf("Error subscribing to %s for %s: %v", eventType, streamerId, err)
+			return err
+		}
+	}
+
 	log.Printf("Successfully subscribed to events for streamer %s", streamerId)
 	return nil
 }
@@ -0,0 +1,185 @@
+package twitch
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"github.com/nicklaw5/helix/v2"
+	"gorm.io/gorm"
+)
+
+// isRequestReward checks if a reward adds songs to the queue
+func isRequestReward(rewardType RewardID) bool {
+	return rewardType == RewardIDRequestSong || rewardType == RewardIDPriorityRequest
+}
+
+// HandleStreamOnline handles stream.online events from EventSub
+func HandleStreamOnline(channelID string) {
+	if rl := GetRewardListener(channelID); rl != nil {
+		log.Printf("Stream of channel %s went online", channelID)
+		rl.setStreamOffline(false)
+	}
+}
+
+// HandleStreamOffline handles stream.offline events from EventSub
+func HandleStreamOffline(channelID string) {
+	if rl := GetRewardListener(channelID); rl != nil {
+		log.Printf("Stream of channel %s went offline", channelID)
+		rl.setStreamOffline(true)
+	}
+}
+
+// refreshStreamStatus polls Helix for the stream status, in case an EventSub notification was missed
+func (rl *RewardListener) refreshStreamStatus() {
+	client := rl.client
+	if client == nil {
+		return // The listener was invalidated
+	}
+
+	resp, err := client.GetStreams(&helix.StreamsParams{
+		UserIDs: []string{rl.streamer.ChannelID},
+	})
+	if err != nil {
+		log.Printf("Error checking stream status of channel %s: %v", rl.streamer.ChannelID, err)
+		return
+	}
+	if resp.Error != "" {
+		log.Printf("Helix API error checking stream status of channel %s: %s - %s", rl.streamer.ChannelID, resp.Error, resp.ErrorMessage)
+		return
+	}
+
+	rl.setStreamOffline(len(resp.Data.Streams) == 0)
+}
+
+// setStreamOffline records the stream status, clears stream bans once the stream ends and updates the reward pauses
+func (rl *RewardListener) setStreamOffline(offline bool) {
+	rl.pauseMutex.Lock()
+	rl.streamOffline = offline
+	rl.pauseMutex.Unlock()
+
+	if offline {
+		if database := db.GetDB(); database != nil {
+			rl.clearStreamBans(database)
+		}
+	}
+
+	rl.UpdateRewardPauses()
+}
+
+// setPlaybackIdle records whether Spotify is playing and updates the reward pauses when it changes
+func (rl *RewardListener) setPlaybackIdle(idle bool) {
+	rl.pauseMutex.Lock()
+	changed := rl.playbackIdle != idle
+	rl.playbackIdle = idle
+	rl.pauseMutex.Unlock()
+
+	if changed {
+		rl.UpdateRewardPauses()
+	}
+}
+
+// checkRequestsOpen checks the requests switch and the queue limits of the streamer.
+// Returns the rejection reason and chat message, or empty strings if requests are accepted.
+func (rl *RewardListener) checkRequestsOpen(database *gorm.DB) (string, string) {
+	if db.AreRequestsClosed(database, rl.streamer.ID) {
+		return "requests_closed", "заказы сейчас закрыты"
+	}
+
+	if maxLength := db.GetMaxQueueLength(database, rl.streamer.ID); maxLength > 0 && rl.queue.Len() >= maxLength {
+		return "queue_full", fmt.Sprintf("очередь заполнена (%d треков), попробуй позже", maxLength)
+	}
+
+	if maxDuration := db.GetMaxQueueDuration(database, rl.streamer.ID); maxDuration > 0 && rl.queue.Duration() >= time.Duration(maxDuration)*time.Second {
+		return "queue_full", fmt.Sprintf("очередь заполнена (%s музыки), попробуй позже", formatDuration(maxDuration))
+	}
+
+	return "", ""
+}
+
+// UpdateRewardPauses pauses the rewards that can't be served right now and resumes the others.
+// All rewards pause while the stream is offline, skip, ban and volume rewards also pause while Spotify
+// isn't playing, and request rewards pause while requests are closed or the queue is full. Requests stay
+// open while Spotify is idle, since they are held until a device appears.
+func (rl *RewardListener) UpdateRewardPauses() {
+	database := db.GetDB()
+	if database == nil {
+		return
+	}
+
+	requestsOpen := true
+	if reason, _ := rl.checkRequestsOpen(database); reason != "" {
+		requestsOpen = false
+	}
+
+	// Work out the wanted states under the lock; the Twitch calls happen outside it
+	var changed []RewardID
+	rl.pauseMutex.Lock()
+	for _, rewardType := range rewardTypes {
+		var paused bool
+		if isRequestReward(rewardType) {
+			paused = rl.streamOffline || !requestsOpen
+		} else {
+			paused = rl.streamOffline || rl.playbackIdle
+		}
+
+		rl.wantedPaused[rewardType] = paused
+		if current, known := rl.pausedRewards[rewardType]; !known || current != paused {
+			changed = append(changed, rewardType)
+		}
+	}
+	rl.pauseMutex.Unlock()
+
+	for _, rewardType := range changed {
+		rl.pushRewardPause(rewardType)
+	}
+}
+
+// pushRewardPause brings the pause state of a reward on Twitch to the wanted state. Only one call per
+// reward is in flight; if the wanted state changes meanwhile, that call's owner pushes the new state too.
+func (rl *RewardListener) pushRewardPause(rewardType RewardID) {
+	twitchID := rl.rewardTwitchID(rewardType)
+	if twitchID == "" {
+		return
+	}
+
+	for {
+		rl.pauseMutex.Lock()
+		paused := rl.wantedPaused[rewardType]
+		current, known := rl.pausedRewards[rewardType]
+		if rl.pausing[rewardType] || (known && current == paused) {
+			rl.pauseMutex.Unlock()
+			return
+		}
+		rl.pausing[rewardType] = true
+		rl.pauseMutex.Unlock()
+
+		err := rl.setRewardPaused(twitchID, paused)
+
+		rl.pauseMutex.Lock()
+		rl.pausing[rewardType] = false
+		if err == nil {
+			rl.pausedRewards[rewardType] = paused
+		}
+		rl.pauseMutex.Unlock()
+
+		if err != nil {
+			log.Printf("Error setting %s reward paused=%t for streamer %d: %v", RewardTypeName(rewardType), paused, rl.streamer.ID, err)
+			return
+		}
+		log.Printf("Set %s reward paused=%t for streamer %d", RewardTypeName(rewardType), paused, rl.streamer.ID)
+	}
+}
+
+// setRewardPaused pauses or resumes a custom reward on Twitch.
+// The helix client can't set is_paused, so this goes through a raw request.
+func (rl *RewardListener) setRewardPaused(twitchID string, paused bool) error {
+	query := url.Values{}
+	query.Set("broadcaster_id", rl.streamer.ChannelID)
+	query.Set("id", twitchID)
+
+	return rl.helixRequest(http.MethodPatch, "/channel_points/custom_rewards", query, map[string]bool{"is_paused": paused}, nil)
+}
@@ -0,0 +1,180 @@
+package twitch
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// Chatter identifies the viewer behind a chat message or reward redemption
+type Chatter struct {
+	ID     string
+	Name   string
+	Badges []Badge // Only available for chat messages
+	Bits   int     // Bits cheered with the chat message
+}
+
+// UserRole represents a viewer's privilege level, ordered from least to most privileged
+type UserRole int
+
+const (
+	RoleEveryone UserRole = iota
+	RoleFollower
+	RoleSubscriber
+	RoleVIP
+	RoleModerator
+	RoleBotModerator
+	RoleBroadcaster
+)
+
+// roleFromPermission converts a db permission level to a role
+func roleFromPermission(permission string) UserRole {
+	switch permission {
+	case db.PermissionFollower:
+		return RoleFollower
+	case db.PermissionSubscriber:
+		return RoleSubscriber
+	case db.PermissionVIP:
+		return RoleVIP
+	case db.PermissionModerator:
+		return RoleModerator
+	case db.PermissionBotModerator:
+		return RoleBotModerator
+	case db.PermissionBroadcaster:
+		return RoleBroadcaster
+	default:
+		return RoleEveryone
+	}
+}
+
+// hasBadge checks if the chatter carries a badge from the given set
+func (c *Chatter) hasBadge(setID string) bool {
+	for _, badge := range c.Badges {
+		if badge.SetID == setID {
+			return true
+		}
+	}
+	return false
+}
+
+// badgeRole returns the highest role that can be derived from the chatter's badges
+func (c *Chatter) badgeRole() UserRole {
+	switch {
+	case c.hasBadge("broadcaster"):
+		return RoleBroadcaster
+	case c.hasBadge("moderator"):
+		return RoleModerator
+	case c.hasBadge("vip"):
+		return RoleVIP
+	case c.hasBadge("subscriber"), c.hasBadge("founder"):
+		return RoleSubscriber
+	default:
+		return RoleEveryone
+	}
+}
+
+// hasPermission checks if a chatter meets the required role.
+// Follower status is only looked up through Helix when it decides the outcome.
+func (rl *RewardListener) hasPermission(c *Chatter, required UserRole) bool {
+	if required == RoleEveryone {
+		return true
+	}
+
+	role := c.badgeRole()
+	if c.ID == rl.streamer.ChannelID {
+		role = RoleBroadcaster
+	}
+
+	if role < RoleBotModerator {
+		database := db.GetDB()
+		if database != nil && db.IsBotModerator(database, rl.streamer.ID, c.ID) {
+			role = RoleBotModerator
+		}
+	}
+
+	if role >= required {
+		return true
+	}
+
+	if required == RoleFollower {
+		return rl.isFollower(c.ID)
+	}
+
+	return false
+}
+
+// isFollower checks through Helix if a user follows the channel
+func (rl *RewardListener) isFollower(userID string) bool {
+	resp, err := rl.client.GetChannelFollows(&helix.GetChannelFollowsParams{
+		BroadcasterID: rl.streamer.ChannelID,
+		UserID:        userID,
+	})
+	if err != nil {
+		log.Printf("Error checking follow status of %s in channel %s: %v", userID, rl.streamer.ChannelID, err)
+		return false
+	}
+
+	if resp.Error != "" {
+		log.Printf("Helix API error checking follow status of %s: %s - %s", userID, resp.Error, resp.ErrorMessage)
+		rl.checkMissingScope(resp.ResponseCommon, "moderator:read:followers")
+		return false
+	}
+
+	return len(resp.Data.Channels) > 0
+}
+
+// permissionDeniedMessage describes who may use a command with the given permission
+func permissionDeniedMessage(permission string) string {
+	switch permission {
+	case db.PermissionFollower:
+		return "This command is available to followers and above"
+	case db.PermissionSubscriber:
+		return "This command is available to subscribers and above"
+	case db.PermissionVIP:
+		return "This command is available to VIPs, moderators and the broadcaster only"
+	case db.PermissionModerator:
+		return "This command is available to moderators and the broadcaster only"
+	case db.PermissionBotModerator:
+		return "This command is available to bot moderators and the broadcaster only; Twitch moderators need to be added as bot moderators"
+	case db.PermissionBroadcaster:
+		return "This command is available to the broadcaster only"
+	default:
+		return "You are not allowed to use this command"
+	}
+}
+
+// checkMissingScope records a scope as missing when Helix rejected a call with 401 or 403, which happens for
+// tokens granted before the bot asked for the scope. The streamer is asked once to log in again.
+func (rl *RewardListener) checkMissingScope(resp helix.ResponseCommon, scope string) {
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return
+	}
+
+	rl.scopeMutex.Lock()
+	known := rl.missingScopes[scope]
+	rl.missingScopes[scope] = true
+	rl.scopeMutex.Unlock()
+
+	if known {
+		return
+	}
+	log.Printf("Twitch token of streamer %d lacks the %s scope", rl.streamer.ID, scope)
+	rl.sendMessage(fmt.Sprintf("@%s the bot is missing the %s permission, so some viewers are denied. Please log in to the bot again to grant it", rl.streamer.Name, scope))
+}
+
+// MissingScopes returns the Twitch scopes the streamer has to log in again for
+func (rl *RewardListener) MissingScopes() []string {
+	rl.scopeMutex.Lock()
+	defer rl.scopeMutex.Unlock()
+
+	scopes := []string{}
+	for scope := range rl.missingScopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
@@ -0,0 +1,116 @@
+package twitch
+
+import (
+	"log"
+	"time"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+)
+
+// Playback watcher timings
+const (
+	PlaybackPollInterval = 10 * time.Second // How often the player is polled
+	QueuePushThreshold   = 25 * time.Second // Remaining time of the current track at which the next request is handed to Spotify
+)
+
+// idleTrackID marks pushes made while nothing was playing
+const idleTrackID = "idle"
+
+// startPlaybackWatcher polls the player until the listener is invalidated
+func (rl *RewardListener) startPlaybackWatcher() {
+	ticker := time.NewTicker(PlaybackPollInterval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-rl.done:
+				log.Printf("Stopped playback watcher for streamer %d", rl.streamer.ID)
+				return
+			case <-ticker.C:
+				rl.observePlayback()
+			}
+		}
+	}()
+}
+
+// observePlayback records when a requested track starts playing and
+// hands the next queued request to Spotify when the current track nears its end
+func (rl *RewardListener) observePlayback() {
+	rl.expireHeldRequests()
+
+	current, err := rl.spotifyClient.GetCurrentTrack()
+	if err != nil {
+		return
+	}
+
+	rl.setPlaybackIdle(current == nil || current.Item == nil || !current.Playing)
+
+	if current == nil || current.Item == nil {
+		// Nothing is playing; queue one request so it is ready when playback starts
+		if rl.lastPushedTrackID != idleTrackID && rl.pushNextRequest() {
+			rl.lastPushedTrackID = idleTrackID
+		}
+		return
+	}
+
+	trackID := string(current.Item.ID)
+
+	// A device is playing again; hand the held requests over without waiting for the track to end
+	if rl.setDeviceActive() && rl.pushNextRequest() {
+		rl.lastPushedTrackID = trackID
+	}
+
+	if trackID != rl.lastPlayingTrackID {
+		rl.lastPlayingTrackID = trackID
+		rl.markTrackPlayed(trackID)
+		rl.recordTrackPlayed(trackID, current.Item.Name, time.Now().Add(-time.Duration(current.Progress)*time.Millisecond))
+	}
+
+	if !current.Playing || rl.lastPushedTrackID == trackID {
+		return
+	}
+
+	remaining := time.Duration(current.Item.Duration-current.Progress) * time.Millisecond
+	if remaining <= QueuePushThreshold && rl.pushNextRequest() {
+		rl.lastPushedTrackID = trackID
+	}
+}
+
+// markTrackPlayed records that a track started playing
+func (rl *RewardListener) markTrackPlayed(trackID string) {
+	database := db.GetDB()
+	if database == nil {
+		return
+	}
+
+	if err := db.MarkTrackPlayed(database, rl.streamer.ID, trackID); err != nil {
+		log.Printf("Error marking track %s as played for streamer %d: %v", trackID, rl.streamer.ID, err)
+	}
+}
+
+// skipCurrentTrack skips the playing track and records the skip in the request log
+func (rl *RewardListener) skipCurrentTrack(reason string) error {
+	current, err := rl.spotifyClient.GetCurrentTrack()
+	if err != nil {
+		log.Printf("Error getting current track before skip: %v", err)
+	}
+
+	if err := rl.spotifyClient.NextTrack(); err != nil {
+		return err
+	}
+
+	if current == nil || current.Item == nil {
+		return nil
+	}
+
+	database := db.GetDB()
+	if database == nil {
+		return nil
+	}
+
+	if err := db.MarkTrackSkipped(database, rl.streamer.ID, string(current.Item.ID), reason); err != nil {
+		log.Printf("Error marking track %s as skipped for streamer %d: %v", current.Item.ID, rl.streamer.ID, err)
+	}
+	return nil
+}
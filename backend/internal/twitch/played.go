@@ -0,0 +1,71 @@
+package twitch
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"gorm.io/gorm"
+)
+
+// recentlyPlayedSyncLimit is how many of the streamer's recently played tracks are merged into the ledger per sync
+const recentlyPlayedSyncLimit = 50
+
+// recordTrackPlayed adds a track that started playing at the given time to the streamer's played ledger
+func (rl *RewardListener) recordTrackPlayed(trackID, trackName string, playedAt time.Time) {
+	database := db.GetDB()
+	if database == nil {
+		return
+	}
+
+	if err := db.RecordTrackPlayed(database, rl.streamer.ID, trackID, trackName, playedAt); err != nil {
+		log.Printf("Error recording played track: %v", err)
+	}
+}
+
+// syncRecentlyPlayed merges the streamer's Spotify listening history into the played ledger,
+// covering tracks the playback watcher missed, for example while the server was down
+func (rl *RewardListener) syncRecentlyPlayed() {
+	items, err := rl.spotifyClient.GetRecentlyPlayed(recentlyPlayedSyncLimit)
+	if err != nil {
+		log.Printf("Error getting recently played tracks for streamer %d: %v", rl.streamer.ID, err)
+		return
+	}
+
+	for _, item := range items {
+		rl.recordTrackPlayed(string(item.Track.ID), item.Track.Name, item.PlayedAt)
+	}
+}
+
+// cleanupPlayedTracks removes ledger entries too old to matter for any hold or cooldown
+func (rl *RewardListener) cleanupPlayedTracks() {
+	database := db.GetDB()
+	if database == nil {
+		return
+	}
+
+	if _, err := db.DeletePlayedTracksBefore(database, rl.streamer.ID, time.Now().Add(-cooldownRetention)); err != nil {
+		log.Printf("Error cleaning up played tracks: %v", err)
+	}
+}
+
+// checkDuplicate rejects tracks that actually played on the channel within the streamer's duplicate hold.
+// Returns the rejection reason and chat message, or empty strings if the track may be requested.
+func (rl *RewardListener) checkDuplicate(database *gorm.DB, trackID string) (string, string) {
+	hold := db.GetDuplicateHold(database, rl.streamer.ID)
+	if hold <= 0 {
+		return "", ""
+	}
+
+	played, err := db.GetPlayedTrack(database, rl.streamer.ID, trackID)
+	if err != nil {
+		log.Printf("Error checking played ledger: %v", err)
+		return "", ""
+	}
+	if played == nil || time.Since(played.PlayedAt) >= time.Duration(hold)*time.Second {
+		return "", ""
+	}
+
+	return "duplicate", fmt.Sprintf("этот трек уже играл за последние %s", formatDuration(hold))
+}
@@ -0,0 +1,64 @@
+package twitch
+
+import (
+	"log"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	spotifylib "github.com/zmb3/spotify/v2"
+	"gorm.io/gorm"
+)
+
+// checkPlaylistAllowlist checks if a track is in one of the streamer's allowed playlists.
+// Returns the rejection reason and chat message, or empty strings if the track passes.
+func (rl *RewardListener) checkPlaylistAllowlist(database *gorm.DB, track *spotifylib.FullTrack) (string, string) {
+	playlists, err := db.GetAllowedPlaylists(database, rl.streamer.ID)
+	if err != nil {
+		log.Printf("Error loading allowed playlists: %v", err)
+		return "internal_error", "произошла ошибка при обработке запроса"
+	}
+
+	if len(playlists) == 0 {
+		return "not_in_playlist", "заказы открыты только из плейлистов стримера, но ни один плейлист не выбран"
+	}
+
+	for _, playlist := range playlists {
+		contains, err := rl.spotifyClient.PlaylistContains(spotifylib.ID(playlist.PlaylistID), track)
+		if err != nil {
+			log.Printf("Error checking playlist %s: %v", playlist.PlaylistID, err)
+			continue
+		}
+		if contains {
+			return "", ""
+		}
+	}
+
+	return "not_in_playlist", "этого трека нет в разрешённых плейлистах стримера"
+}
+
+// refreshAllowedPlaylists refetches the streamer's allowed playlists whose cached contents are stale
+func (rl *RewardListener) refreshAllowedPlaylists() {
+	database := db.GetDB()
+	if database == nil || !db.IsPlaylistAllowlistEnabled(database, rl.streamer.ID) {
+		return
+	}
+
+	playlists, err := db.GetAllowedPlaylists(database, rl.streamer.ID)
+	if err != nil {
+		log.Printf("Error loading allowed playlists: %v", err)
+		return
+	}
+
+	var ids []spotifylib.ID
+	for _, playlist := range playlists {
+		ids = append(ids, spotifylib.ID(playlist.PlaylistID))
+	}
+
+	if err := rl.spotifyClient.RefreshPlaylists(ids); err != nil {
+		log.Printf("Error refreshing allowed playlists for streamer %d: %v", rl.streamer.ID, err)
+	}
+}
+
+// GetPlaylistName looks up a Spotify playlist with the streamer's account and returns its name
+func (rl *RewardListener) GetPlaylistName(playlistID string) (string, error) {
+	return rl.spotifyClient.GetPlaylistName(spotifylib.ID(playlistID))
+}
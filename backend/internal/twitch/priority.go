@@ -0,0 +1,113 @@
+package twitch
+
+import (
+	"log"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// requestTier returns the first configured priority tier the requester qualifies for.
+// Helix lookups are only made for redemptions, which carry no badges.
+func (rl *RewardListener) requestTier(c *Chatter, tiers []string, bitsMin int) string {
+	for _, tier := range tiers {
+		switch tier {
+		case db.PriorityTierBits:
+			if c.Bits > 0 && c.Bits >= bitsMin {
+				return tier
+			}
+		case db.PriorityTierSubscriber:
+			if rl.isChatterSubscriber(c) {
+				return tier
+			}
+		case db.PriorityTierVIP:
+			if c.hasBadge("vip") || (c.Badges == nil && rl.isVIP(c.ID)) {
+				return tier
+			}
+		case db.PriorityTierRegular:
+			return tier
+		}
+	}
+	return db.PriorityTierRegular
+}
+
+// tierRank returns the position of a tier in the configured order; lower ranks play first.
+// Play-next requests rank ahead of every tier, and tiers that aren't enabled rank as regular requests.
+func tierRank(tiers []string, tier string) int {
+	if tier == db.PriorityTierPlayNext {
+		return -1
+	}
+
+	regularRank := len(tiers)
+	for i, t := range tiers {
+		if t == tier {
+			return i
+		}
+		if t == db.PriorityTierRegular {
+			regularRank = i
+		}
+	}
+	return regularRank
+}
+
+// tierLabel returns the chat label of a priority tier
+func tierLabel(tier string) string {
+	switch tier {
+	case db.PriorityTierBits:
+		return "биты"
+	case db.PriorityTierSubscriber:
+		return "подписчик"
+	case db.PriorityTierVIP:
+		return "VIP"
+	case db.PriorityTierPlayNext:
+		return "вне очереди"
+	default:
+		return ""
+	}
+}
+
+// isChatterSubscriber checks if the chatter is subscribed, by badge or, for redemptions, through Helix.
+// Unlike the role checks, VIPs and moderators don't count unless they are subscribed too.
+func (rl *RewardListener) isChatterSubscriber(c *Chatter) bool {
+	return c.hasBadge("subscriber") || c.hasBadge("founder") || (c.Badges == nil && rl.isSubscriber(c.ID))
+}
+
+// isSubscriber checks through Helix if a user is subscribed to the channel
+func (rl *RewardListener) isSubscriber(userID string) bool {
+	resp, err := rl.client.GetSubscriptions(&helix.SubscriptionsParams{
+		BroadcasterID: rl.streamer.ChannelID,
+		UserID:        []string{userID},
+	})
+	if err != nil {
+		log.Printf("Error checking subscription of %s in channel %s: %v", userID, rl.streamer.ChannelID, err)
+		return false
+	}
+
+	if resp.Error != "" {
+		log.Printf("Helix API error checking subscription of %s: %s - %s", userID, resp.Error, resp.ErrorMessage)
+		rl.checkMissingScope(resp.ResponseCommon, "channel:read:subscriptions")
+		return false
+	}
+
+	return len(resp.Data.Subscriptions) > 0
+}
+
+// isVIP checks through Helix if a user is a VIP of the channel
+func (rl *RewardListener) isVIP(userID string) bool {
+	resp, err := rl.client.GetChannelVips(&helix.GetChannelVipsParams{
+		BroadcasterID: rl.streamer.ChannelID,
+		UserID:        userID,
+	})
+	if err != nil {
+		log.Printf("Error checking VIP status of %s in channel %s: %v", userID, rl.streamer.ChannelID, err)
+		return false
+	}
+
+	if resp.Error != "" {
+		log.Printf("Helix API error checking VIP status of %s: %s - %s", userID, resp.Error, resp.ErrorMessage)
+		rl.checkMissingScope(resp.ResponseCommon, "channel:read:vips")
+		return false
+	}
+
+	return len(resp.Data.ChannelsVips) > 0
+}
@@ -0,0 +1,302 @@
+package twitch
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"github.com/emcifuntik/twitch-spotify-request/internal/spotify"
+	spotifylib "github.com/zmb3/spotify/v2"
+)
+
+// QueuedRequest is an accepted song request waiting in the bot-managed queue
+type QueuedRequest struct {
+	RequestID    uint // ID of the db.Request log entry
+	Track        *spotifylib.FullTrack
+	UserID       string
+	UserName     string
+	RedemptionID string // Empty for chat requests
+	RewardID     string
+	Tier         string // db.PriorityTier*
+	Rank         int    // Position of the tier in the streamer's tier order; lower ranks play first
+	QueuedAt     time.Time
+	HeldNotified bool // Set once the requester was told the request waits for a Spotify device
+}
+
+// RequestQueue holds accepted requests until they are handed to Spotify
+type RequestQueue struct {
+	items []*QueuedRequest
+	mutex sync.RWMutex
+}
+
+// NewRequestQueue creates an empty request queue
+func NewRequestQueue() *RequestQueue {
+	return &RequestQueue{}
+}
+
+// Push inserts a request behind all requests of the same or a higher priority
+// and returns its 1-based position
+func (q *RequestQueue) Push(item *QueuedRequest) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	index := len(q.items)
+	for i, queued := range q.items {
+		if queued.Rank > item.Rank {
+			index = i
+			break
+		}
+	}
+
+	q.items = append(q.items[:index], append([]*QueuedRequest{item}, q.items[index:]...)...)
+	return index + 1
+}
+
+// PushFront puts a request back at the head of the queue
+func (q *RequestQueue) PushFront(item *QueuedRequest) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.items = append([]*QueuedRequest{item}, q.items...)
+}
+
+// Pop removes and returns the next request, or nil if the queue is empty
+func (q *RequestQueue) Pop() *QueuedRequest {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.items) == 0 {
+		return nil
+	}
+
+	item := q.items[0]
+	q.items = q.items[1:]
+	return item
+}
+
+// Remove removes a request by its log ID and returns it, or nil if it isn't queued
+func (q *RequestQueue) Remove(requestID uint) *QueuedRequest {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i, item := range q.items {
+		if item.RequestID == requestID {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return item
+		}
+	}
+	return nil
+}
+
+// Move moves a request to a new 1-based position, clamped to the queue bounds
+func (q *RequestQueue) Move(requestID uint, position int) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	index := -1
+	for i, item := range q.items {
+		if item.RequestID == requestID {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return false
+	}
+
+	item := q.items[index]
+	q.items = append(q.items[:index], q.items[index+1:]...)
+
+	target := position - 1
+	if target < 0 {
+		target = 0
+	}
+	if target > len(q.items) {
+		target = len(q.items)
+	}
+
+	q.items = append(q.items[:target], append([]*QueuedRequest{item}, q.items[target:]...)...)
+	return true
+}
+
+// Contains checks if a track is already waiting in the queue
+func (q *RequestQueue) Contains(trackID spotifylib.ID) bool {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	for _, item := range q.items {
+		if item.Track.ID == trackID {
+			return true
+		}
+	}
+	return false
+}
+
+// CountByUser returns how many requests of a viewer are waiting in the queue
+func (q *RequestQueue) CountByUser(userID string) int {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	count := 0
+	for _, item := range q.items {
+		if item.UserID == userID {
+			count++
+		}
+	}
+	return count
+}
+
+// Items returns a snapshot of the queued requests in play order
+func (q *RequestQueue) Items() []QueuedRequest {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	items := make([]QueuedRequest, 0, len(q.items))
+	for _, item := range q.items {
+		items = append(items, *item)
+	}
+	return items
+}
+
+// Duration returns the total length of the queued tracks
+func (q *RequestQueue) Duration() time.Duration {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	var total time.Duration
+	for _, item := range q.items {
+		total += time.Duration(item.Track.Duration) * time.Millisecond
+	}
+	return total
+}
+
+// Len returns the number of queued requests
+func (q *RequestQueue) Len() int {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	return len(q.items)
+}
+
+// pushNextRequest hands the next queued request to Spotify.
+// Returns true if a request was pushed.
+func (rl *RewardListener) pushNextRequest() bool {
+	item := rl.queue.Pop()
+	if item == nil {
+		return false
+	}
+
+	if err := rl.spotifyClient.EnqueueTrack(item.Track.URI); err != nil {
+		if errors.Is(err, spotify.ErrNoActiveDevice) {
+			rl.holdRequest(item)
+			return false
+		}
+		log.Printf("Error handing request %d to Spotify, will retry: %v", item.RequestID, err)
+		rl.queue.PushFront(item)
+		return false
+	}
+
+	rl.setDeviceActive()
+
+	log.Printf("Handed request %d (%s) to Spotify for streamer %d", item.RequestID, item.Track.URI, rl.streamer.ID)
+
+	// Resume the request rewards if the queue has room again
+	rl.UpdateRewardPauses()
+
+	if database := db.GetDB(); database != nil && item.RequestID != 0 {
+		if err := db.UpdateRequestStatus(database, item.RequestID, db.RequestStatusAccepted, ""); err != nil {
+			log.Printf("Error updating request %d: %v", item.RequestID, err)
+		}
+	}
+
+	if item.RedemptionID != "" {
+		if err := rl.updateRedemptionStatus(item.RedemptionID, item.RewardID, "FULFILLED"); err != nil {
+			log.Printf("Error fulfilling redemption %s: %v", item.RedemptionID, err)
+		}
+	}
+
+	return true
+}
+
+// RemoveQueuedRequest removes a request from the queue and refunds its redemption, if any
+func (rl *RewardListener) RemoveQueuedRequest(requestID uint, reason string) bool {
+	item := rl.queue.Remove(requestID)
+	if item == nil {
+		return false
+	}
+
+	rl.refundQueuedRequest(item, reason)
+	rl.sendMessage(fmt.Sprintf("@%s %s removed from the queue", item.UserName, spotify.SongItemToReadable(item.Track)))
+	rl.UpdateRewardPauses()
+	return true
+}
+
+// MoveQueuedRequest moves a request to a new 1-based position in the queue
+func (rl *RewardListener) MoveQueuedRequest(requestID uint, position int) bool {
+	return rl.queue.Move(requestID, position)
+}
+
+// refundQueuedRequest marks a queued request as refunded and cancels its redemption, if any
+func (rl *RewardListener) refundQueuedRequest(item *QueuedRequest, reason string) {
+	if database := db.GetDB(); database != nil && item.RequestID != 0 {
+		if err := db.UpdateRequestStatus(database, item.RequestID, db.RequestStatusRefunded, reason); err != nil {
+			log.Printf("Error updating request %d: %v", item.RequestID, err)
+		}
+	}
+
+	if item.RedemptionID != "" {
+		if err := rl.updateRedemptionStatus(item.RedemptionID, item.RewardID, "CANCELED"); err != nil {
+			log.Printf("Error refunding redemption %s: %v", item.RedemptionID, err)
+		}
+	}
+}
+
+// restoreQueue reloads requests that were still queued when the listener was last stopped
+func (rl *RewardListener) restoreQueue() {
+	database := db.GetDB()
+	if database == nil {
+		return
+	}
+
+	requests, err := db.GetRequestsByStatus(database, rl.streamer.ID, db.RequestStatusQueued)
+	if err != nil {
+		log.Printf("Error loading queued requests for streamer %d: %v", rl.streamer.ID, err)
+		return
+	}
+
+	tiers := db.GetPriorityTiers(database, rl.streamer.ID)
+
+	for _, request := range requests {
+		track, err := rl.spotifyClient.GetTrackByID(request.TrackID)
+		if err != nil {
+			log.Printf("Error restoring queued request %d: %v", request.ID, err)
+			continue
+		}
+
+		rewardID := ""
+		if request.RedemptionID != "" && request.Tier == db.PriorityTierPlayNext {
+			rewardID = rl.rewardTwitchID(RewardIDPriorityRequest)
+		} else if request.RedemptionID != "" {
+			rewardID = rl.rewardTwitchID(RewardIDRequestSong)
+		}
+
+		rl.queue.Push(&QueuedRequest{
+			RequestID:    request.ID,
+			Track:        track,
+			UserID:       request.User.TwitchID,
+			UserName:     request.User.TwitchName,
+			RedemptionID: request.RedemptionID,
+			RewardID:     rewardID,
+			Tier:         request.Tier,
+			Rank:         tierRank(tiers, request.Tier),
+			QueuedAt:     request.RequestTime,
+		})
+	}
+
+	if len(requests) > 0 {
+		log.Printf("Restored %d queued requests for streamer %d", rl.queue.Len(), rl.streamer.ID)
+	}
+}
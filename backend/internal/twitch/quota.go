@@ -0,0 +1,44 @@
+package twitch
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"gorm.io/gorm"
+)
+
+// checkQuota checks the per-viewer request limits of the streamer.
+// Returns the rejection reason and chat message, or empty strings if the request may proceed.
+func (rl *RewardListener) checkQuota(database *gorm.DB, user *Chatter) (string, string) {
+	// The broadcaster is never limited on their own channel
+	if user.ID == rl.streamer.ChannelID {
+		return "", ""
+	}
+
+	if maxPending := db.GetMaxPendingPerUser(database, rl.streamer.ID); maxPending > 0 {
+		if pending := rl.queue.CountByUser(user.ID); pending >= maxPending {
+			return "quota_pending", fmt.Sprintf("у тебя уже %d заказ(ов) в очереди, дождись их воспроизведения", pending)
+		}
+	}
+
+	// The minimum gap between requests is the viewer cooldown
+	if block := GetCooldownManager().GetRemainingCooldown(rl.streamer.ID, []db.CooldownSubject{userCooldownSubject(user)}); block != nil {
+		return "quota_gap", cooldownMessage(block)
+	}
+
+	if maxRequests := db.GetMaxUserRequests(database, rl.streamer.ID); maxRequests > 0 {
+		window := db.GetUserRequestWindow(database, rl.streamer.ID)
+		since := time.Now().Add(-time.Duration(window) * time.Second)
+
+		count, err := db.CountUserRequestsSince(database, rl.streamer.ID, user.ID, since)
+		if err != nil {
+			log.Printf("Error counting requests of %s: %v", user.Name, err)
+		} else if count >= int64(maxRequests) {
+			return "quota_window", fmt.Sprintf("лимит заказов исчерпан (%d за %s)", maxRequests, formatDuration(window))
+		}
+	}
+
+	return "", ""
+}
@@ -0,0 +1,141 @@
+package twitch
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+)
+
+// maxRedemptionsPerPage is the Helix page size limit for redemptions
+const maxRedemptionsPerPage = 50
+
+// missedRedemption is a redemption as returned by the Helix redemptions endpoint
+type missedRedemption struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	UserName   string    `json:"user_name"`
+	UserInput  string    `json:"user_input"`
+	Status     string    `json:"status"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+	Reward     struct {
+		ID string `json:"id"`
+	} `json:"reward"`
+}
+
+// redemptionsPage is a page of the Helix redemptions endpoint
+type redemptionsPage struct {
+	Data       []missedRedemption `json:"data"`
+	Pagination struct {
+		Cursor string `json:"cursor"`
+	} `json:"pagination"`
+}
+
+// recoverRedemptions processes the unfulfilled song request redemptions that arrived while the listener
+// wasn't running. Redemptions already in the request log are skipped and ones older than the streamer's
+// maximum age are refunded. Skips, song bans and volume changes act on whatever plays now rather than what
+// the viewer heard, so they are always refunded.
+func (rl *RewardListener) recoverRedemptions() {
+	database := db.GetDB()
+	if database == nil {
+		return
+	}
+
+	var redemptions []missedRedemption
+	rewardTypes := make(map[string]RewardID)
+	for _, reward := range rl.rewards {
+		rewardTypes[reward.TwitchID] = RewardID(reward.InternalID)
+		pending, err := rl.getUnfulfilledRedemptions(reward.TwitchID)
+		if err != nil {
+			log.Printf("Error getting unfulfilled redemptions of reward %s for streamer %d: %v", reward.TwitchID, rl.streamer.ID, err)
+			continue
+		}
+		redemptions = append(redemptions, pending...)
+	}
+
+	if len(redemptions) == 0 {
+		return
+	}
+
+	var ids []string
+	for _, redemption := range redemptions {
+		ids = append(ids, redemption.ID)
+	}
+	handled, err := db.GetHandledRedemptionIDs(database, rl.streamer.ID, ids)
+	if err != nil {
+		log.Printf("Error checking recovered redemptions for streamer %d: %v", rl.streamer.ID, err)
+		return
+	}
+
+	// Replay in the order viewers redeemed, across all rewards
+	sort.SliceStable(redemptions, func(i, j int) bool {
+		return redemptions[i].RedeemedAt.Before(redemptions[j].RedeemedAt)
+	})
+
+	maxAge := time.Duration(db.GetRedemptionMaxAge(database, rl.streamer.ID)) * time.Second
+	processed, refunded := 0, 0
+
+	for _, redemption := range redemptions {
+		if handled[redemption.ID] {
+			continue
+		}
+
+		rewardType := rewardTypes[redemption.Reward.ID]
+		replayable := rewardType == RewardIDRequestSong || rewardType == RewardIDPriorityRequest
+		if !replayable || (maxAge > 0 && time.Since(redemption.RedeemedAt) > maxAge) {
+			if !globalEventDeduplicator.Claim(EventKindRedemption, redemption.ID) {
+				continue
+			}
+			log.Printf("Refunding missed redemption %s from %s, redeemed at %s", redemption.ID, redemption.UserName, redemption.RedeemedAt.Format(time.RFC3339))
+			if err := rl.updateRedemptionStatus(redemption.ID, redemption.Reward.ID, "CANCELED"); err != nil {
+				log.Printf("Error refunding missed redemption %s: %v", redemption.ID, err)
+			}
+			refunded++
+			continue
+		}
+
+		log.Printf("Processing missed redemption %s from %s", redemption.ID, redemption.UserName)
+		if err := rl.HandleRewardRedemption(redemption.ID, redemption.Reward.ID, redemption.UserID, redemption.UserName, redemption.UserInput); err != nil {
+			log.Printf("Error processing missed redemption %s: %v", redemption.ID, err)
+		}
+		processed++
+	}
+
+	if processed > 0 || refunded > 0 {
+		log.Printf("Recovered missed redemptions for streamer %d: %d processed, %d refunded", rl.streamer.ID, processed, refunded)
+	}
+}
+
+// getUnfulfilledRedemptions returns all unfulfilled redemptions of a reward, oldest first.
+// The helix client has no call for listing redemptions, so this goes through raw requests.
+func (rl *RewardListener) getUnfulfilledRedemptions(rewardID string) ([]missedRedemption, error) {
+	var redemptions []missedRedemption
+	cursor := ""
+
+	for {
+		query := url.Values{}
+		query.Set("broadcaster_id", rl.streamer.ChannelID)
+		query.Set("reward_id", rewardID)
+		query.Set("status", "UNFULFILLED")
+		query.Set("sort", "OLDEST")
+		query.Set("first", fmt.Sprint(maxRedemptionsPerPage))
+		if cursor != "" {
+			query.Set("after", cursor)
+		}
+
+		var page redemptionsPage
+		if err := rl.helixRequest(http.MethodGet, "/channel_points/custom_rewards/redemptions", query, nil, &page); err != nil {
+			return nil, err
+		}
+
+		redemptions = append(redemptions, page.Data...)
+		if page.Pagination.Cursor == "" || len(page.Data) == 0 {
+			return redemptions, nil
+		}
+		cursor = page.Pagination.Cursor
+	}
+}
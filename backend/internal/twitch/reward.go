@@ -23,6 +23,13 @@ type Reward struct {
 	Prompt string `json:"prompt"`
 }
 
+type StreamStatusEvent struct {
+	BroadcasterUserID    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	Type                 string `json:"type,omitempty"` // Only set for stream.online
+}
+
 type ChatMessageEvent struct {
 	BroadcasterUserID           string  `json:"broadcaster_user_id"`
 	BroadcasterUserLogin        string  `json:"broadcaster_user_login"`
@@ -0,0 +1,395 @@
+package twitch
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emcifuntik/twitch-spotify-request/internal/db"
+	"github.com/emcifuntik/twitch-spotify-request/internal/spotify"
+	"github.com/nicklaw5/helix/v2"
+	"gorm.io/gorm"
+)
+
+// rewardTypes lists the bot rewards in setup order
+var rewardTypes = []RewardID{
+	RewardIDRequestSong,
+	RewardIDSkipSong,
+	RewardIDPriorityRequest,
+	RewardIDBanSong,
+	RewardIDVolumeUp,
+	RewardIDVolumeDown,
+}
+
+// rewardTypeNames are the API names of the reward types
+var rewardTypeNames = map[RewardID]string{
+	RewardIDRequestSong:     "request_song",
+	RewardIDSkipSong:        "skip_song",
+	RewardIDPriorityRequest: "priority_request",
+	RewardIDBanSong:         "ban_song",
+	RewardIDVolumeUp:        "volume_up",
+	RewardIDVolumeDown:      "volume_down",
+}
+
+// StreamBanMaxDuration bounds a ban-song-for-stream block in case the end of the stream is missed
+const StreamBanMaxDuration = 12 * time.Hour
+
+// RewardTypeName returns the API name of a reward type
+func RewardTypeName(rewardType RewardID) string {
+	return rewardTypeNames[rewardType]
+}
+
+// ParseRewardType returns the reward type with the given API name
+func ParseRewardType(name string) (RewardID, bool) {
+	for rewardType, typeName := range rewardTypeNames {
+		if typeName == name {
+			return rewardType, true
+		}
+	}
+	return 0, false
+}
+
+// DefaultRewardSettings returns the settings a reward is created with until the streamer configures it.
+// Only the request and skip rewards are enabled by default; the others are created once the streamer enables them.
+func DefaultRewardSettings(rewardType RewardID, streamerID uint) db.RewardSettings {
+	switch rewardType {
+	case RewardIDSkipSong:
+		return db.RewardSettings{
+			Title:           fmt.Sprintf("Skip song (Bot %d)", streamerID),
+			Cost:            1000,
+			Prompt:          "Skip current song",
+			BackgroundColor: "#00aaaa",
+			Enabled:         true,
+		}
+	case RewardIDPriorityRequest:
+		return db.RewardSettings{
+			Title:           fmt.Sprintf("Priority song request (Bot %d)", streamerID),
+			Cost:            1500,
+			Prompt:          "Enter artist and song name to play it before other requests",
+			BackgroundColor: "#ff8800",
+		}
+	case RewardIDBanSong:
+		return db.RewardSettings{
+			Title:           fmt.Sprintf("Ban song for stream (Bot %d)", streamerID),
+			Cost:            2000,
+			Prompt:          "Skip the current song and ban it for the rest of the stream",
+			BackgroundColor: "#aa0000",
+		}
+	case RewardIDVolumeUp:
+		return db.RewardSettings{
+			Title:           fmt.Sprintf("Volume up (Bot %d)", streamerID),
+			Cost:            200,
+			Prompt:          "Turn the music up",
+			BackgroundColor: "#00aa00",
+		}
+	case RewardIDVolumeDown:
+		return db.RewardSettings{
+			Title:           fmt.Sprintf("Volume down (Bot %d)", streamerID),
+			Cost:            200,
+			Prompt:          "Turn the music down",
+			BackgroundColor: "#0000aa",
+		}
+	default:
+		return db.RewardSettings{
+			Title:           fmt.Sprintf("Request song (Bot %d)", streamerID),
+			Cost:            300,
+			Prompt:          "Enter artist and song name to add request",
+			BackgroundColor: "#aaaa00",
+			Enabled:         true,
+		}
+	}
+}
+
+// rewardRequiresInput checks if viewers must type text when redeeming a reward
+func rewardRequiresInput(rewardType RewardID) bool {
+	return rewardType == RewardIDRequestSong || rewardType == RewardIDPriorityRequest
+}
+
+// rewardSettings returns the streamer's settings for a reward type
+func (rl *RewardListener) rewardSettings(rewardType RewardID) db.RewardSettings {
+	defaults := DefaultRewardSettings(rewardType, rl.streamer.ID)
+
+	database := db.GetDB()
+	if database == nil {
+		return defaults
+	}
+	return db.GetRewardSettings(database, rl.streamer.ID, int8(rewardType), defaults)
+}
+
+// RewardInfo describes a bot reward and its settings
+type RewardInfo struct {
+	Type     RewardID
+	TwitchID string // Empty if the reward hasn't been created on Twitch
+	Settings db.RewardSettings
+}
+
+// Rewards returns the bot rewards of the streamer with their settings
+func (rl *RewardListener) Rewards() []RewardInfo {
+	var rewards []RewardInfo
+	for _, rewardType := range rewardTypes {
+		rewards = append(rewards, RewardInfo{
+			Type:     rewardType,
+			TwitchID: rl.rewardTwitchID(rewardType),
+			Settings: rl.rewardSettings(rewardType),
+		})
+	}
+	return rewards
+}
+
+// setupReward adopts the bot reward of the given type if it already exists on Twitch, or creates it
+func (rl *RewardListener) setupReward(rewardType RewardID) error {
+	settings := rl.rewardSettings(rewardType)
+
+	rewards, err := rl.client.GetCustomRewards(&helix.GetCustomRewardsParams{
+		BroadcasterID:         rl.streamer.ChannelID,
+		OnlyManageableRewards: true,
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to get existing rewards: %w", err)
+	}
+
+	for _, reward := range rewards.Data.ChannelCustomRewards {
+		if strings.EqualFold(reward.Title, settings.Title) {
+			log.Printf("Reward %s already exists for streamer %d", RewardTypeName(rewardType), rl.streamer.ID)
+			// Save the existing reward ID
+			if err := rl.saveReward(rewardType, reward.ID); err != nil {
+				log.Printf("Error saving existing %s reward to database: %v", RewardTypeName(rewardType), err)
+			}
+			return nil // Reward already exists, no need to create again
+		}
+	}
+
+	response, err := rl.client.CreateCustomReward(&helix.ChannelCustomRewardsParams{
+		BroadcasterID:                rl.streamer.ChannelID,
+		Title:                        settings.Title,
+		Cost:                         settings.Cost,
+		Prompt:                       settings.Prompt,
+		IsUserInputRequired:          rewardRequiresInput(rewardType),
+		BackgroundColor:              settings.BackgroundColor,
+		IsEnabled:                    true,
+		IsGlobalCooldownEnabled:      settings.GlobalCooldown > 0,
+		GlobalCooldownSeconds:        settings.GlobalCooldown,
+		IsMaxPerStreamEnabled:        settings.MaxPerStream > 0,
+		MaxPerStream:                 settings.MaxPerStream,
+		IsMaxPerUserPerStreamEnabled: settings.MaxPerUserPerStream > 0,
+		MaxPerUserPerStream:          settings.MaxPerUserPerStream,
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to create %s reward: %w", RewardTypeName(rewardType), err)
+	}
+
+	if response.Error != "" {
+		return fmt.Errorf("failed to create %s reward: %s - %s", RewardTypeName(rewardType), response.Error, response.ErrorMessage)
+	}
+
+	if len(response.Data.ChannelCustomRewards) == 0 {
+		return fmt.Errorf("no reward data returned")
+	}
+
+	rewardID := response.Data.ChannelCustomRewards[0].ID
+	log.Printf("Created %s reward with ID: %s", RewardTypeName(rewardType), rewardID)
+
+	// Save to database
+	if err := rl.saveReward(rewardType, rewardID); err != nil {
+		log.Printf("Error saving %s reward to database: %v", RewardTypeName(rewardType), err)
+	}
+
+	return nil
+}
+
+// UpdateRewardSettings stores new settings for a reward type and pushes them to Twitch, creating or
+// deleting the reward when it gets enabled or disabled. The stored settings are rolled back if Twitch
+// rejects the change, so both stay in sync.
+func (rl *RewardListener) UpdateRewardSettings(rewardType RewardID, settings db.RewardSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	database := db.GetDB()
+	if database == nil {
+		return fmt.Errorf("database not available")
+	}
+
+	previous := rl.rewardSettings(rewardType)
+	if err := db.SetRewardSettings(database, rl.streamer.ID, int8(rewardType), settings); err != nil {
+		return err
+	}
+
+	if err := rl.pushRewardSettings(rewardType, settings); err != nil {
+		if rollbackErr := db.SetRewardSettings(database, rl.streamer.ID, int8(rewardType), previous); rollbackErr != nil {
+			log.Printf("Error rolling back settings of %s reward for streamer %d: %v", RewardTypeName(rewardType), rl.streamer.ID, rollbackErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// pushRewardSettings applies stored reward settings on Twitch
+func (rl *RewardListener) pushRewardSettings(rewardType RewardID, settings db.RewardSettings) error {
+	twitchID := rl.rewardTwitchID(rewardType)
+	if !settings.Enabled {
+		if twitchID != "" {
+			return rl.removeReward(rewardType, twitchID)
+		}
+		return nil
+	}
+
+	if twitchID == "" {
+		// Newly enabled; create the reward with the new settings
+		return rl.setupReward(rewardType)
+	}
+
+	// Keep the enabled state the streamer set on Twitch
+	current, err := rl.client.GetCustomRewards(&helix.GetCustomRewardsParams{
+		BroadcasterID: rl.streamer.ChannelID,
+		ID:            twitchID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get %s reward from Twitch: %w", RewardTypeName(rewardType), err)
+	}
+	if current.Error != "" || len(current.Data.ChannelCustomRewards) == 0 {
+		return fmt.Errorf("failed to get %s reward from Twitch: %s - %s", RewardTypeName(rewardType), current.Error, current.ErrorMessage)
+	}
+
+	resp, err := rl.client.UpdateCustomReward(&helix.UpdateChannelCustomRewardsParams{
+		ID:                           twitchID,
+		BroadcasterID:                rl.streamer.ChannelID,
+		Title:                        settings.Title,
+		Cost:                         settings.Cost,
+		Prompt:                       settings.Prompt,
+		IsUserInputRequired:          rewardRequiresInput(rewardType),
+		BackgroundColor:              settings.BackgroundColor,
+		IsEnabled:                    current.Data.ChannelCustomRewards[0].IsEnabled,
+		IsGlobalCooldownEnabled:      settings.GlobalCooldown > 0,
+		GlobalCooldownSeconds:        settings.GlobalCooldown,
+		IsMaxPerStreamEnabled:        settings.MaxPerStream > 0,
+		MaxPerStream:                 settings.MaxPerStream,
+		IsMaxPerUserPerStreamEnabled: settings.MaxPerUserPerStream > 0,
+		MaxPerUserPerStream:          settings.MaxPerUserPerStream,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update %s reward on Twitch: %w", RewardTypeName(rewardType), err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("failed to update %s reward on Twitch: %s - %s", RewardTypeName(rewardType), resp.Error, resp.ErrorMessage)
+	}
+
+	log.Printf("Updated %s reward %s for streamer %d", RewardTypeName(rewardType), twitchID, rl.streamer.ID)
+	return nil
+}
+
+// removeReward deletes a disabled reward from Twitch and forgets it
+func (rl *RewardListener) removeReward(rewardType RewardID, twitchID string) error {
+	resp, err := rl.client.DeleteCustomRewards(&helix.DeleteCustomRewardsParams{
+		BroadcasterID: rl.streamer.ChannelID,
+		ID:            twitchID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete %s reward on Twitch: %w", RewardTypeName(rewardType), err)
+	}
+	if resp.Error != "" && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete %s reward on Twitch: %s - %s", RewardTypeName(rewardType), resp.Error, resp.ErrorMessage)
+	}
+
+	if database := db.GetDB(); database != nil {
+		if err := database.Where("reward_streamer = ? AND reward_internal_id = ?", rl.streamer.ID, int8(rewardType)).Delete(&db.Reward{}).Error; err != nil {
+			log.Printf("Error deleting %s reward from database: %v", RewardTypeName(rewardType), err)
+		}
+	}
+
+	var rewards []db.Reward
+	for _, reward := range rl.rewards {
+		if RewardID(reward.InternalID) != rewardType {
+			rewards = append(rewards, reward)
+		}
+	}
+	rl.rewards = rewards
+
+	log.Printf("Deleted disabled %s reward %s for streamer %d", RewardTypeName(rewardType), twitchID, rl.streamer.ID)
+	return nil
+}
+
+// handleSongBan skips the current song and blocks it until the stream ends
+func (rl *RewardListener) handleSongBan(userName, redemptionID, rewardID string) error {
+	current, err := rl.spotifyClient.GetCurrentTrack()
+	if err != nil || current == nil || current.Item == nil {
+		rl.sendMessage(fmt.Sprintf("@%s сейчас ничего не играет, баллы возвращены", userName))
+		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+	}
+
+	database := db.GetDB()
+	if database == nil {
+		rl.sendMessage(fmt.Sprintf("@%s произошла ошибка при бане трека", userName))
+		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+	}
+
+	track := current.Item
+	songName := spotify.SongItemToReadable(track)
+	expiresAt := time.Now().Add(StreamBanMaxDuration)
+	err = db.AddBlock(database, rl.streamer.ID, db.BlockTypeTrack, string(track.ID), songName, db.BlockOptions{
+		ExpiresAt:  &expiresAt,
+		Reason:     "забанен до конца стрима за баллы канала",
+		AddedBy:    userName,
+		StreamOnly: true,
+	})
+	if err != nil {
+		log.Printf("Error banning track %s for the stream: %v", track.ID, err)
+		rl.sendMessage(fmt.Sprintf("@%s произошла ошибка при бане трека", userName))
+		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+	}
+
+	if err := rl.skipCurrentTrack(fmt.Sprintf("banned for the stream by %s via reward", userName)); err != nil {
+		log.Printf("Error skipping banned track %s: %v", track.ID, err)
+	}
+
+	rl.sendMessage(fmt.Sprintf("@%s %s забанен до конца стрима", userName, songName))
+	return rl.updateRedemptionStatus(redemptionID, rewardID, "FULFILLED")
+}
+
+// handleVolumeReward moves the volume one configured step up (direction 1) or down (direction -1)
+func (rl *RewardListener) handleVolumeReward(userName, redemptionID, rewardID string, direction int) error {
+	database := db.GetDB()
+	if database == nil {
+		rl.sendMessage(fmt.Sprintf("@%s произошла ошибка при изменении громкости", userName))
+		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+	}
+
+	current, err := rl.spotifyClient.GetVolume()
+	if err != nil {
+		log.Printf("Error getting volume for volume reward: %v", err)
+		rl.sendMessage(fmt.Sprintf("@%s не удалось узнать текущую громкость, баллы возвращены", userName))
+		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+	}
+
+	minVolume, maxVolume := db.GetVolumeRewardBounds(database, rl.streamer.ID)
+	volume := max(minVolume, min(maxVolume, current+direction*db.GetVolumeRewardStep(database, rl.streamer.ID)))
+	if volume == current || (direction > 0 && volume < current) || (direction < 0 && volume > current) {
+		rl.sendMessage(fmt.Sprintf("@%s громкость уже на пределе (%d%%), баллы возвращены", userName, current))
+		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+	}
+
+	if err := rl.spotifyClient.SetVolume(volume); err != nil {
+		log.Printf("Error setting volume to %d%% for volume reward: %v", volume, err)
+		rl.sendMessage(fmt.Sprintf("@%s произошла ошибка при изменении громкости", userName))
+		return rl.updateRedemptionStatus(redemptionID, rewardID, "CANCELED")
+	}
+
+	rl.sendMessage(fmt.Sprintf("@%s громкость: %d%%", userName, volume))
+	return rl.updateRedemptionStatus(redemptionID, rewardID, "FULFILLED")
+}
+
+// clearStreamBans removes the blocks that only last for the current stream
+func (rl *RewardListener) clearStreamBans(database *gorm.DB) {
+	removed, err := db.DeleteStreamOnlyBlocks(database, rl.streamer.ID)
+	if err != nil {
+		log.Printf("Error clearing stream bans for streamer %d: %v", rl.streamer.ID, err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("Cleared %d stream bans for streamer %d", removed, rl.streamer.ID)
+	}
+}